@@ -0,0 +1,326 @@
+package minirest
+
+import (
+	"bufio"
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Access log formats
+const (
+	AccessLogJSON   = "json"
+	AccessLogLogfmt = "logfmt"
+)
+
+// Redacted replace values of redacted headers and url queries in access log
+const Redacted = "[REDACTED]"
+
+// DefaultRedactHeaders is headers redacted in access log when AccessLogOption.RedactHeaders is nil
+var DefaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// DefaultRedactQueries is url queries redacted in access log when AccessLogOption.RedactQueries is nil
+var DefaultRedactQueries = []string{"token", "access_token", "api_key", "password", "secret"}
+
+// AccessLogOption set options for access log
+type AccessLogOption struct {
+	// Logger is logger access logs are written to.
+	// If nil, logs are written to Output in Format
+	Logger *slog.Logger
+	// Output is writer of access logs when Logger is nil, default is os.Stdout
+	Output io.Writer
+	// Format is AccessLogJSON or AccessLogLogfmt, default is AccessLogJSON
+	Format string
+	// SampleRate is fraction of requests logged, between 0 and 1, default 0 log all requests.
+	// Responses with status 400 and above are always logged
+	SampleRate float64
+	// RequestIDHeader is header of request ID, default is X-Request-ID.
+	// Request without it is given random ID, which is set to request and response headers
+	RequestIDHeader string
+	// Set to true for reading remote IP from X-Forwarded-For and X-Real-IP headers,
+	// only when the app is behind trusted proxy
+	TrustProxy bool
+	// Headers is request headers logged with each request
+	Headers []string
+	// RedactHeaders is headers logged as Redacted, default is DefaultRedactHeaders
+	RedactHeaders []string
+	// RedactQueries is url queries logged as Redacted, default is DefaultRedactQueries
+	RedactQueries []string
+	// SkipRoutes is route patterns not logged, such as "/health".
+	// Pattern can be prefixed by method, such as "GET /health"
+	SkipRoutes []string
+}
+
+// accessLogger is AccessLogOption prepared for logging
+type accessLogger struct {
+	opt           AccessLogOption
+	logger        *slog.Logger
+	redactHeaders map[string]bool
+	redactQueries map[string]bool
+	skip          map[string]bool
+}
+
+// AccessLog enable access log for all routes, including Handler and Mount routes.
+// Options are read on each request, so AccessLog can be called after AddController
+func (mn *Minirest) AccessLog(opt AccessLogOption) {
+	mn.accessLog = newAccessLogger(opt)
+}
+
+func newAccessLogger(opt AccessLogOption) *accessLogger {
+	if opt.RequestIDHeader == "" {
+		opt.RequestIDHeader = "X-Request-ID"
+	}
+
+	if opt.RedactHeaders == nil {
+		opt.RedactHeaders = DefaultRedactHeaders
+	}
+
+	if opt.RedactQueries == nil {
+		opt.RedactQueries = DefaultRedactQueries
+	}
+
+	al := &accessLogger{
+		opt:           opt,
+		logger:        opt.Logger,
+		redactHeaders: make(map[string]bool),
+		redactQueries: make(map[string]bool),
+		skip:          make(map[string]bool),
+	}
+
+	if al.logger == nil {
+		out := opt.Output
+		if out == nil {
+			out = os.Stdout
+		}
+
+		if opt.Format == AccessLogLogfmt {
+			al.logger = slog.New(slog.NewTextHandler(out, nil))
+		} else {
+			al.logger = slog.New(slog.NewJSONHandler(out, nil))
+		}
+	}
+
+	for _, header := range opt.RedactHeaders {
+		al.redactHeaders[http.CanonicalHeaderKey(header)] = true
+	}
+
+	for _, query := range opt.RedactQueries {
+		al.redactQueries[query] = true
+	}
+
+	for _, route := range opt.SkipRoutes {
+		al.skip[route] = true
+	}
+
+	return al
+}
+
+// accessLogHandler log requests to route method and path, using override
+// options of Endpoints if not nil. Options of Minirest are read on each request,
+// so Minirest.AccessLog can be called after AddController
+func (mn *Minirest) accessLogHandler(scope routeScope, method, path string, next httprouter.Handle) httprouter.Handle {
+	if scope.noAccessLog {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		al := scope.accessLog
+		if al == nil {
+			al = mn.accessLog
+		}
+
+		if al == nil || al.skip[path] || al.skip[method+" "+path] {
+			next(w, r, p)
+			return
+		}
+
+		id := r.Header.Get(al.opt.RequestIDHeader)
+		if id == "" {
+			id = newRequestID()
+			r.Header.Set(al.opt.RequestIDHeader, id)
+			w.Header().Set(al.opt.RequestIDHeader, id)
+		}
+
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		defer func() {
+			// panic is logged as 500 written by PanicHandler
+			if rcv := recover(); rcv != nil {
+				sw.status = http.StatusInternalServerError
+				al.log(r, path, id, sw, time.Since(start))
+				panic(rcv)
+			}
+
+			al.log(r, path, id, sw, time.Since(start))
+		}()
+
+		next(sw, r, p)
+	}
+}
+
+func (al *accessLogger) log(r *http.Request, route, id string, sw *statusWriter, latency time.Duration) {
+	status := sw.Status()
+	if status < http.StatusBadRequest && al.opt.SampleRate > 0 && al.opt.SampleRate < 1 && rand.Float64() >= al.opt.SampleRate {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", r.Method),
+		slog.String("route", route),
+		slog.String("path", r.URL.Path),
+	}
+
+	if r.URL.RawQuery != "" {
+		attrs = append(attrs, slog.String("query", al.query(r)))
+	}
+
+	attrs = append(attrs,
+		slog.Int("status", status),
+		slog.Int64("bytes", sw.bytes),
+		slog.Duration("latency", latency),
+		slog.String("remote_ip", al.remoteIP(r)),
+		slog.String("user_agent", r.UserAgent()),
+		slog.String("request_id", id),
+	)
+
+	if len(al.opt.Headers) > 0 {
+		var headers []any
+		for _, key := range al.opt.Headers {
+			value := r.Header.Get(key)
+			if value == "" {
+				continue
+			}
+
+			if al.redactHeaders[http.CanonicalHeaderKey(key)] {
+				value = Redacted
+			}
+
+			headers = append(headers, slog.String(key, value))
+		}
+
+		if len(headers) > 0 {
+			attrs = append(attrs, slog.Group("headers", headers...))
+		}
+	}
+
+	level := slog.LevelInfo
+	if status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	} else if status >= http.StatusBadRequest {
+		level = slog.LevelWarn
+	}
+
+	al.logger.LogAttrs(context.Background(), level, "request", attrs...)
+}
+
+// query return url queries of r, with redacted values replaced
+func (al *accessLogger) query(r *http.Request) string {
+	query := r.URL.Query()
+	for key, values := range query {
+		if !al.redactQueries[key] {
+			continue
+		}
+
+		for i := range values {
+			values[i] = Redacted
+		}
+	}
+
+	return strings.ReplaceAll(query.Encode(), url.QueryEscape(Redacted), Redacted)
+}
+
+func (al *accessLogger) remoteIP(r *http.Request) string {
+	if al.opt.TrustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+
+		if real := r.Header.Get("X-Real-IP"); real != "" {
+			return real
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	crand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// statusWriter record status and size of response written by handler
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+
+	return n, err
+}
+
+// Status return written status, 200 if handler doesn't write anything
+func (w *statusWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+
+	return w.status
+}
+
+// Flush implement http.Flusher for Server-Sent Events
+func (w *statusWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implement http.Hijacker for WebSocket, hijacked connection is logged with status 101
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("minirest: response writer doesn't support hijacking")
+	}
+
+	w.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// Unwrap return the underlying http.ResponseWriter, for http.ResponseController
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,154 @@
+package minirest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// logLines decode JSON access logs written into buf
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+
+		entry := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("decode log %q: %v", line, err)
+		}
+
+		lines = append(lines, entry)
+	}
+
+	return lines
+}
+
+func TestAccessLog(t *testing.T) {
+	buf := new(bytes.Buffer)
+	mn := newTestApp("GET", "/users/:id", func(id int) *ResponseBuilder { return echo(id) })
+	mn.AccessLog(AccessLogOption{Output: buf, Headers: []string{"Authorization", "X-Tenant"}})
+
+	r := httptest.NewRequest("GET", "/users/1?token=abc&page=2", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("User-Agent", "test")
+	r.Header.Set("Authorization", "Bearer secret")
+	r.Header.Set("X-Tenant", "acme")
+	w := httptest.NewRecorder()
+	mn.ServeHTTP(w, r)
+
+	lines := logLines(t, buf)
+	if len(lines) != 1 {
+		t.Fatalf("got %d logs", len(lines))
+	}
+
+	entry := lines[0]
+	want := map[string]interface{}{
+		"level":      "INFO",
+		"msg":        "request",
+		"method":     "GET",
+		"route":      "/users/:id",
+		"path":       "/users/1",
+		"query":      "page=2&token=[REDACTED]",
+		"status":     float64(200),
+		"bytes":      float64(w.Body.Len()),
+		"remote_ip":  "10.0.0.1",
+		"user_agent": "test",
+		"request_id": w.Header().Get("X-Request-ID"),
+	}
+
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("%s = %v, want %v", key, entry[key], value)
+		}
+	}
+
+	if len(w.Header().Get("X-Request-ID")) != 16 {
+		t.Errorf("request ID = %q", w.Header().Get("X-Request-ID"))
+	}
+
+	headers, _ := entry["headers"].(map[string]interface{})
+	if headers["Authorization"] != Redacted || headers["X-Tenant"] != "acme" {
+		t.Errorf("headers = %v", entry["headers"])
+	}
+}
+
+func TestAccessLogLevelsAndRequestID(t *testing.T) {
+	silenceLog(t)
+	buf := new(bytes.Buffer)
+	mn := newTestApp("GET", "/items/:id", func(id int) *ResponseBuilder {
+		if id == 0 {
+			panic("boom")
+		}
+
+		return echo(id)
+	})
+	mn.AccessLog(AccessLogOption{Output: buf, TrustProxy: true})
+
+	r := httptest.NewRequest("GET", "/items/x", nil)
+	r.Header.Set("X-Request-ID", "req-1")
+	r.Header.Set("X-Forwarded-For", "1.2.3.4, 10.0.0.1")
+	mn.ServeHTTP(httptest.NewRecorder(), r)
+	mn.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/items/0", nil))
+
+	lines := logLines(t, buf)
+	if len(lines) != 2 {
+		t.Fatalf("got %d logs", len(lines))
+	}
+
+	if lines[0]["level"] != "WARN" || lines[0]["status"] != float64(400) || lines[0]["request_id"] != "req-1" || lines[0]["remote_ip"] != "1.2.3.4" {
+		t.Errorf("bad request log = %v", lines[0])
+	}
+
+	if lines[1]["level"] != "ERROR" || lines[1]["status"] != float64(500) {
+		t.Errorf("panic log = %v", lines[1])
+	}
+}
+
+type healthController struct{}
+
+func (ctrl *healthController) Endpoints() *Endpoints {
+	ep := new(Endpoints)
+	ep.SkipAccessLog()
+	ep.GET("/health", func() *ResponseBuilder { return echo("ok") })
+
+	return ep
+}
+
+func TestAccessLogSkip(t *testing.T) {
+	buf := new(bytes.Buffer)
+	mn := newTestApp("GET", "/users", func() *ResponseBuilder { return echo("users") })
+	mn.AddController(new(healthController))
+	mn.Handler("GET", "/metrics", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	mn.AccessLog(AccessLogOption{Output: buf, Format: AccessLogLogfmt, SkipRoutes: []string{"GET /metrics"}})
+
+	for _, path := range []string{"/health", "/metrics", "/users"} {
+		serve(mn, "GET", path, nil)
+	}
+
+	got := buf.String()
+	if strings.Count(got, "\n") != 1 || !strings.Contains(got, "route=/users ") || !strings.Contains(got, "status=200") {
+		t.Errorf("logs = %q", got)
+	}
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	buf := new(bytes.Buffer)
+	mn := newTestApp("GET", "/users/:id", func(id int) *ResponseBuilder { return echo(id) })
+	mn.AccessLog(AccessLogOption{Output: buf, SampleRate: 0.000001})
+
+	for i := 0; i < 10; i++ {
+		serve(mn, "GET", "/users/1", nil)
+	}
+
+	serve(mn, "GET", "/users/x", nil)
+	lines := logLines(t, buf)
+	if len(lines) != 1 || lines[0]["status"] != float64(400) {
+		t.Errorf("logs = %v", lines)
+	}
+}
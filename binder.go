@@ -0,0 +1,246 @@
+package minirest
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// requestBinder bind request into struct, analysed once when endpoint is registered.
+// Fields tagged with path are bound from path variables, fields tagged with query
+// are bound from url queries, and the rest of the struct is decoded from JSON body
+// following encoding/json rules. Query tag can have required option, example:
+//
+//	type GetUsers struct {
+//		Group string   `path:"group"`
+//		Page  int      `query:"page,required"`
+//		Tags  []string `query:"tag"`
+//	}
+type requestBinder struct {
+	typ    reflect.Type
+	fields []fieldBinding
+	// body is true when there are fields decoded from JSON body
+	body bool
+}
+
+type fieldBinding struct {
+	index    []int
+	source   string
+	name     string
+	typ      reflect.Type
+	required bool
+	set      setFunc
+}
+
+// setFunc set field from values of key
+type setFunc func(field reflect.Value, key string, values []string) error
+
+// newRequestBinder analyse t registered with path.
+// Body is only decoded when withBody is true, and t that can't be bound is reported by panic
+func newRequestBinder(t reflect.Type, path string, withBody bool) *requestBinder {
+	binder := &requestBinder{typ: t}
+	if t.Kind() != reflect.Struct {
+		if !withBody {
+			panic("minirest: request type " + t.String() + " must be struct for endpoint without body")
+		}
+
+		binder.body = true
+		return binder
+	}
+
+	binder.analyse(t, nil, withBody)
+
+	vars := make(map[string]bool)
+	for _, name := range pathVars(path) {
+		vars[name] = true
+	}
+
+	for _, field := range binder.fields {
+		if field.source == BindPath && !vars[field.name] {
+			panic("minirest: path variable " + field.name + " of " + t.String() + " doesn't exist in " + path)
+		}
+	}
+
+	return binder
+}
+
+func (binder *requestBinder) analyse(t reflect.Type, index []int, withBody bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldIndex := append(append([]int(nil), index...), i)
+		path, hasPath := field.Tag.Lookup("path")
+		query, hasQuery := field.Tag.Lookup("query")
+		if !hasPath && !hasQuery {
+			// tags of embedded struct are bound as if they're declared in t
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				binder.analyse(field.Type, fieldIndex, withBody)
+				continue
+			}
+
+			if field.PkgPath == "" && field.Tag.Get("json") != "-" {
+				binder.body = binder.body || withBody
+			}
+
+			continue
+		}
+
+		if field.PkgPath != "" {
+			panic("minirest: field " + field.Name + " of " + t.String() + " is unexported and can't be bound")
+		}
+
+		binding := fieldBinding{index: fieldIndex, source: BindPath, name: path, typ: field.Type}
+		if hasQuery {
+			name, opts := parseTag(query)
+			binding.source = BindQuery
+			binding.name = name
+			binding.required = strings.Contains(","+opts+",", ",required,")
+		}
+
+		if binding.name == "" {
+			binding.name = field.Name
+		}
+
+		binding.set = setterFor(field.Type, binding.source == BindQuery)
+		if binding.set == nil {
+			panic("minirest: field " + field.Name + " of " + t.String() + " has unsupported type " + field.Type.String())
+		}
+
+		binder.fields = append(binder.fields, binding)
+	}
+}
+
+// bindings describe the binder for route introspection
+func (binder *requestBinder) bindings() []Binding {
+	var bindings []Binding
+	for _, field := range binder.fields {
+		bindings = append(bindings, Binding{Source: field.source, Name: field.name, Type: field.typ.String(), typ: field.typ, required: field.required})
+	}
+
+	if binder.body {
+		bindings = append(bindings, Binding{Source: BindBody, Type: binder.typ.String(), typ: binder.typ})
+	}
+
+	return bindings
+}
+
+// bind bind r into dest. Body is decoded first, so path variables and url queries
+// always take precedence, and empty body is left as zero value.
+// Fields tagged with path or query are never set from body
+func (binder *requestBinder) bind(dest reflect.Value, r *http.Request, pathVars httprouter.Params) error {
+	if binder.body {
+		if err := json.NewDecoder(r.Body).Decode(dest.Addr().Interface()); err != nil && err != io.EOF {
+			return err
+		}
+
+		// fields bound from path variables and url queries are not part of body,
+		// so they're reset when body has them, even if they're missing from the URL
+		for _, field := range binder.fields {
+			dest.FieldByIndex(field.index).Set(reflect.Zero(field.typ))
+		}
+	}
+
+	if len(binder.fields) == 0 {
+		return nil
+	}
+
+	query := r.URL.Query()
+	for _, field := range binder.fields {
+		var values []string
+		if field.source == BindPath {
+			values = []string{pathVars.ByName(field.name)}
+		} else {
+			values = query[field.name]
+		}
+
+		if len(values) == 0 {
+			if field.required {
+				return errors.New(field.name + " is required")
+			}
+
+			continue
+		}
+
+		if err := field.set(dest.FieldByIndex(field.index), field.name, values); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setterFor return setFunc for t, nil for unsupported types.
+// Slices are only supported for url queries, which can have multiple values,
+// and single values are assigned by the same assignFunc as callback parameters
+func setterFor(t reflect.Type, multi bool) setFunc {
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return firstValue(assignText)
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		elem := setterFor(t.Elem(), multi)
+		if elem == nil {
+			return nil
+		}
+
+		return func(field reflect.Value, key string, values []string) error {
+			ptr := reflect.New(t.Elem())
+			if err := elem(ptr.Elem(), key, values); err != nil {
+				return err
+			}
+
+			field.Set(ptr)
+			return nil
+		}
+	case reflect.Slice:
+		if !multi {
+			return nil
+		}
+
+		elem := setterFor(t.Elem(), false)
+		if elem == nil {
+			return nil
+		}
+
+		return func(field reflect.Value, key string, values []string) error {
+			slice := reflect.MakeSlice(t, len(values), len(values))
+			for i, value := range values {
+				if err := elem(slice.Index(i), key, []string{value}); err != nil {
+					return err
+				}
+			}
+
+			field.Set(slice)
+			return nil
+		}
+	}
+
+	if assign := assignerFor(t.Kind()); assign != nil {
+		return firstValue(assign)
+	}
+
+	return nil
+}
+
+// firstValue return setFunc assigning the first value with assign
+func firstValue(assign assignFunc) setFunc {
+	return func(field reflect.Value, key string, values []string) error {
+		return assign(field, key, values[0])
+	}
+}
+
+func assignText(field reflect.Value, key, value string) error {
+	if err := field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)); err != nil {
+		return errors.New(key + " is not valid " + field.Type().String() + ": " + err.Error())
+	}
+
+	return nil
+}
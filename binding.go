@@ -0,0 +1,276 @@
+package minirest
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+
+	"github.com/gorilla/schema"
+	"github.com/julienschmidt/httprouter"
+)
+
+// bindingPlan is how parameters of callback are bound from request,
+// analysed once when endpoint is registered
+type bindingPlan struct {
+	callback reflect.Value
+	params   []paramPlan
+	// decoder is nil when there is no param bound from url queries
+	decoder *schema.Decoder
+}
+
+type paramPlan struct {
+	typ reflect.Type
+	// source is empty until the parameter is bound
+	source string
+	// ptr is true for pointer parameter, the value is allocated before binding
+	ptr bool
+	// assign convert path variable, nil for unsupported types which are left zero
+	assign assignFunc
+}
+
+// assignFunc set param from path variable key with value
+type assignFunc func(param reflect.Value, key, value string) error
+
+// newBindingPlan analyse callback registered with path.
+// Path variables are matched with parameters by index, skipping struct parameters,
+// and the first struct parameter that is not bound is decoded from url queries,
+// see handleWithoutBody. With body, the first parameter is decoded from JSON body.
+// It panics when parameters can't be bound, so the endpoint fails at registration instead of on every request
+func newBindingPlan(callback interface{}, path string, withBody bool) *bindingPlan {
+	plan := &bindingPlan{callback: reflect.ValueOf(callback)}
+
+	t := plan.callback.Type()
+	plan.params = make([]paramPlan, t.NumIn())
+	for i := range plan.params {
+		plan.params[i] = paramPlan{typ: t.In(i)}
+	}
+
+	if withBody {
+		if len(plan.params) == 0 {
+			panic("minirest: callback of " + path + " has no parameter for request body")
+		}
+
+		plan.params[0].source = BindBody
+		plan.params[0].ptr = plan.params[0].typ.Kind() == reflect.Ptr
+		return plan
+	}
+
+	// more path variables than parameters can't be matched
+	vars := len(pathVars(path))
+	if vars > len(plan.params) {
+		panic("minirest: callback of " + path + " has less parameters than path variables")
+	}
+
+	for i := 0; i < vars; i++ {
+		param := &plan.params[i]
+		typ := param.typ
+		if typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+			param.ptr = true
+		}
+
+		// struct is only for url queries
+		if typ.Kind() == reflect.Struct {
+			param.ptr = false
+			continue
+		}
+
+		param.source = BindPath
+		param.assign = pathAssignerFor(typ.Kind())
+	}
+
+	for i := range plan.params {
+		param := &plan.params[i]
+		if param.source != "" {
+			continue
+		}
+
+		typ := param.typ
+		if typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+			param.ptr = true
+		}
+
+		if typ.Kind() == reflect.Struct {
+			param.source = BindQuery
+			plan.decoder = schema.NewDecoder()
+			break
+		}
+
+		param.ptr = false
+	}
+
+	for i, param := range plan.params {
+		// parameter bound from nothing can't be passed to callback
+		if param.source == "" {
+			panic("minirest: parameter " + strconv.Itoa(i) + " of callback of " + path + " is not bound to path variable nor url queries")
+		}
+	}
+
+	return plan
+}
+
+// bindings describe the plan for route introspection
+func (plan *bindingPlan) bindings(path string) []Binding {
+	names := pathVars(path)
+	bindings := make([]Binding, len(plan.params))
+	for i, param := range plan.params {
+		bindings[i] = Binding{Source: param.source, Type: param.typ.String(), typ: param.typ}
+		if param.source == BindPath {
+			bindings[i].Name = names[i]
+		}
+	}
+
+	return bindings
+}
+
+// bind build callback arguments from path variables and url queries.
+// Path variables are bound before url queries, and returned error is written as bad request
+func (plan *bindingPlan) bind(pathVars httprouter.Params, query url.Values) ([]reflect.Value, error) {
+	args := make([]reflect.Value, len(plan.params))
+	for i := range plan.params {
+		if plan.params[i].source != BindPath {
+			continue
+		}
+
+		arg, err := plan.params[i].bindPath(pathVars[i])
+		if err != nil {
+			return nil, err
+		}
+
+		args[i] = arg
+	}
+
+	for i := range plan.params {
+		param := &plan.params[i]
+		if param.source != BindQuery {
+			continue
+		}
+
+		arg := reflect.New(param.typ)
+		dest := arg
+		if param.ptr {
+			arg.Elem().Set(reflect.New(param.typ.Elem()))
+			dest = arg.Elem()
+		}
+
+		if err := plan.decoder.Decode(dest.Interface(), query); err != nil {
+			return nil, err
+		}
+
+		args[i] = arg.Elem()
+	}
+
+	return args, nil
+}
+
+func (param *paramPlan) bindPath(pair httprouter.Param) (reflect.Value, error) {
+	arg := reflect.New(param.typ).Elem()
+	dest := arg
+	if param.ptr {
+		arg.Set(reflect.New(param.typ.Elem()))
+		dest = arg.Elem()
+	}
+
+	if param.assign != nil {
+		if err := param.assign(dest, pair.Key, pair.Value); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+
+	return arg, nil
+}
+
+// bindBody build callback argument from JSON body.
+// The argument is returned as is, so the args slice built by caller doesn't escape
+func (plan *bindingPlan) bindBody(r *http.Request) (reflect.Value, error) {
+	arg := reflect.New(plan.params[0].typ)
+	if err := bodyDecoder(r.Body, arg); err != nil {
+		return reflect.Value{}, err
+	}
+
+	return arg.Elem(), nil
+}
+
+// call call callback with args.
+// Callback only can have one return value, and it must be *ResponseBuilder
+func (plan *bindingPlan) call(args []reflect.Value) *ResponseBuilder {
+	return plan.callback.Call(args)[0].Interface().(*ResponseBuilder)
+}
+
+// assignerFor return assignFunc for kind, nil for unsupported kinds.
+// It's shared by callback parameters and typed request fields, see setterFor
+func assignerFor(kind reflect.Kind) assignFunc {
+	switch kind {
+	case reflect.String:
+		return assignString
+	case reflect.Bool:
+		return assignBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return assignInt
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return assignUint
+	case reflect.Float32, reflect.Float64:
+		return assignFloat
+	}
+
+	return nil
+}
+
+// pathAssignerFor return assignFunc for path variable of callback.
+// Right now the supported kinds are only string, int and float64, other kinds are left zero
+func pathAssignerFor(kind reflect.Kind) assignFunc {
+	switch kind {
+	case reflect.String, reflect.Int, reflect.Float64:
+		return assignerFor(kind)
+	}
+
+	return nil
+}
+
+func assignString(param reflect.Value, key, value string) error {
+	param.SetString(value)
+	return nil
+}
+
+func assignBool(param reflect.Value, key, value string) error {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return errors.New(key + " is not type bool")
+	}
+
+	param.SetBool(b)
+	return nil
+}
+
+func assignInt(param reflect.Value, key, value string) error {
+	i, err := strconv.ParseInt(value, 10, param.Type().Bits())
+	if err != nil {
+		return errors.New(key + " is not type " + param.Kind().String())
+	}
+
+	param.SetInt(i)
+	return nil
+}
+
+func assignUint(param reflect.Value, key, value string) error {
+	u, err := strconv.ParseUint(value, 10, param.Type().Bits())
+	if err != nil {
+		return errors.New(key + " is not type " + param.Kind().String())
+	}
+
+	param.SetUint(u)
+	return nil
+}
+
+func assignFloat(param reflect.Value, key, value string) error {
+	f, err := strconv.ParseFloat(value, param.Type().Bits())
+	if err != nil {
+		return errors.New(key + " is not type " + param.Kind().String())
+	}
+
+	param.SetFloat(f)
+	return nil
+}
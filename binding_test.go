@@ -0,0 +1,313 @@
+package minirest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/schema"
+	"github.com/julienschmidt/httprouter"
+)
+
+// legacyHandleWithoutBody is handleWithoutBody before binding plans,
+// it inspect callback on every request
+func (mn *Minirest) legacyHandleWithoutBody(callback interface{}) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, pathVars httprouter.Params) {
+		writer := new(ResponseBuilder)
+		decoder := schema.NewDecoder()
+		var params []reflect.Value
+		// get all parameters in callback
+		m := reflect.ValueOf(callback)
+		for i := 0; i < m.Type().NumIn(); i++ {
+			params = append(params, reflect.New(m.Type().In(i)))
+		}
+
+		// match all path variables with callback parameters index and assign callback param
+		// right now the supported type are only int and float64
+		for i, pair := range pathVars {
+			param := params[i].Elem()
+
+			// exclude type struct as it's only for filtering (url query)
+			if param.Kind() == reflect.Struct {
+
+				continue
+			}
+
+			// handle pointer param
+			if param.Kind() == reflect.Ptr {
+				if param.Type().Elem().Kind() == reflect.Struct {
+
+					continue
+				}
+
+				param.Set(reflect.New(param.Type().Elem()))
+				if err := legacyAssign(param.Elem(), pair); err != nil {
+					writer.BadRequest(err.Error())
+					mn.writeResponse(w, r, writer)
+					return
+				}
+
+				params[i] = param
+				continue
+			}
+
+			if err := legacyAssign(param, pair); err != nil {
+				writer.BadRequest(err.Error())
+				mn.writeResponse(w, r, writer)
+				return
+			}
+
+			params[i] = param
+		}
+
+		// extract url queries
+		queriesVars := r.URL.Query()
+		// iterate callback params, find the one with type struct. Note that once param
+		// with type struct is found, iteration will stop and parse the url queries to it
+		// so there will be only one param with type struct is allowed as queries
+		for i, param := range params {
+			// if param can set, then it must be path variable, skip!
+			if param.CanSet() {
+				continue
+			}
+
+			if v := param.Elem(); v.Kind() == reflect.Ptr {
+				if v.Type().Elem().Kind() != reflect.Struct {
+					continue
+				}
+
+				// v now a pointer to a struct
+				// initialize the struct
+				v.Set(reflect.New(v.Type().Elem()))
+
+				if err := decoder.Decode(v.Interface(), queriesVars); err != nil {
+					writer.BadRequest(err.Error())
+					mn.writeResponse(w, r, writer)
+					return
+				}
+
+				params[i] = param.Elem()
+
+				break
+			}
+
+			if param.Elem().Kind() == reflect.Struct {
+				if err := decoder.Decode(param.Interface(), queriesVars); err != nil {
+					writer.BadRequest(err.Error())
+					mn.writeResponse(w, r, writer)
+					return
+				}
+
+				params[i] = param.Elem()
+
+				break
+			}
+		}
+
+		// call callback
+		// note that callback only can have one return value, and it must be *ResponseBuilder
+		returns := reflect.ValueOf(callback).Call(params)
+		respBuilder := returns[0].Interface().(*ResponseBuilder)
+		mn.writeResponse(w, r, respBuilder)
+	}
+}
+
+// legacyAssign is assignParam before binding plans, unsupported kinds are left zero
+func legacyAssign(param reflect.Value, pair httprouter.Param) error {
+	if assign := pathAssignerFor(param.Kind()); assign != nil {
+		return assign(param, pair.Key, pair.Value)
+	}
+
+	return nil
+}
+
+// legacyHandleWithBody is handleWithBody before binding plans
+func (mn *Minirest) legacyHandleWithBody(callback interface{}) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		writer := new(ResponseBuilder)
+		m := reflect.ValueOf(callback)
+
+		// get parameter in callback
+		// only the first parameter are considered the real parameter,
+		// no matter how much params you have
+		param := reflect.New(m.Type().In(0))
+		if err := bodyDecoder(r.Body, param); err != nil {
+			writer.BadRequest(err.Error())
+			mn.writeResponse(w, r, writer)
+			return
+		}
+
+		returns := m.Call([]reflect.Value{param.Elem()})
+		respBody := returns[0].Interface().(*ResponseBuilder)
+		mn.writeResponse(w, r, respBody)
+	}
+}
+
+// bindingCases are routes served by both binding plan and legacy implementation
+var bindingCases = []struct {
+	name     string
+	method   string
+	path     string
+	target   string
+	body     string
+	callback interface{}
+}{
+	{"path int", "GET", "/items/:id", "/items/12", "", func(id int) *ResponseBuilder { return echo(id) }},
+	{"path invalid", "GET", "/items/:id", "/items/x", "", func(id int) *ResponseBuilder { return echo(id) }},
+	{"path pointer", "GET", "/items/:id", "/items/3", "", func(id *float64) *ResponseBuilder { return echo(id) }},
+	{"path unsupported", "GET", "/items/:id", "/items/3", "", func(id uint) *ResponseBuilder { return echo(id) }},
+	{"path and query", "GET", "/items/:id", "/items/3?name=a&limit=2", "", func(id int, q testQuery) *ResponseBuilder { return echo([]interface{}{id, q}) }},
+	{"path and pointer query", "GET", "/items/:id", "/items/3?limit=2", "", func(id int, q *testQuery) *ResponseBuilder { return echo([]interface{}{id, q}) }},
+	{"query error", "GET", "/items", "/items?limit=x", "", func(q testQuery) *ResponseBuilder { return echo(q) }},
+	{"body", "POST", "/items", "/items", `{"name":"a"}`, func(b testBody) *ResponseBuilder { return echo(b) }},
+	{"body pointer", "PUT", "/items", "/items", `{"age":2}`, func(b *testBody) *ResponseBuilder { return echo(b) }},
+	{"body invalid", "PATCH", "/items", "/items", `{`, func(b testBody) *ResponseBuilder { return echo(b) }},
+}
+
+// serveLegacy serve target with legacy implementation registered at path
+func serveLegacy(mn *Minirest, method, path, target, body string, callback interface{}) *httptest.ResponseRecorder {
+	var handle httprouter.Handle
+	if method == "GET" {
+		handle = mn.legacyHandleWithoutBody(callback)
+	} else {
+		handle = mn.legacyHandleWithBody(callback)
+	}
+
+	mn.router.Handle(method, path, handle)
+	return serve(mn, method, target, strings.NewReader(body))
+}
+
+func TestBindingPlanMatchLegacy(t *testing.T) {
+	silenceLog(t)
+	for _, tt := range bindingCases {
+		t.Run(tt.name, func(t *testing.T) {
+			got := serve(newTestApp(tt.method, tt.path, tt.callback), tt.method, tt.target, strings.NewReader(tt.body))
+			want := serveLegacy(New(), tt.method, tt.path, tt.target, tt.body, tt.callback)
+			if got.Code != want.Code || got.Body.String() != want.Body.String() {
+				t.Errorf("got %d %s, legacy %d %s", got.Code, got.Body, want.Code, want.Body)
+			}
+		})
+	}
+}
+
+func TestBindingPlanBindings(t *testing.T) {
+	plan := newBindingPlan(func(id int, q *testQuery) *ResponseBuilder { return nil }, "/items/:id", false)
+	got := plan.bindings("/items/:id")
+	want := []Binding{
+		{Source: BindPath, Name: "id", Type: "int", typ: reflect.TypeOf(0)},
+		{Source: BindQuery, Type: "*minirest.testQuery", typ: reflect.TypeOf(&testQuery{})},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("bindings = %+v, want %+v", got, want)
+	}
+}
+
+// callbacks that can't be bound panic when registered, not on every request
+func TestBindingPlanPanics(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		callback interface{}
+		want     string
+	}{
+		{
+			name: "unbound", method: "GET", path: "/items",
+			callback: func(name string) *ResponseBuilder { return echo(name) },
+			want:     "minirest: parameter 0 of callback of /items is not bound to path variable nor url queries",
+		},
+		{
+			// only the first struct param is decoded, the second is unbound
+			name: "second struct query", method: "GET", path: "/items",
+			callback: func(a, b testQuery) *ResponseBuilder { return echo(a) },
+			want:     "minirest: parameter 1 of callback of /items is not bound to path variable nor url queries",
+		},
+		{
+			name: "unbound after path variable", method: "DELETE", path: "/items/:id",
+			callback: func(id int, extra string) *ResponseBuilder { return echo(id) },
+			want:     "minirest: parameter 1 of callback of /items/:id is not bound to path variable nor url queries",
+		},
+		{
+			// struct is skipped at path index, so the path variable is not bound to any parameter
+			name: "struct at path index", method: "GET", path: "/items/:id",
+			callback: func(q testQuery, id int) *ResponseBuilder { return echo(id) },
+			want:     "minirest: parameter 1 of callback of /items/:id is not bound to path variable nor url queries",
+		},
+		{
+			name: "more path variables", method: "GET", path: "/items/:a/:b",
+			callback: func(a int) *ResponseBuilder { return echo(a) },
+			want:     "minirest: callback of /items/:a/:b has less parameters than path variables",
+		},
+		{
+			name: "no body parameter", method: "POST", path: "/items",
+			callback: func() *ResponseBuilder { return echo(nil) },
+			want:     "minirest: callback of /items has no parameter for request body",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if got := recover(); got != tt.want {
+					t.Errorf("panic = %v, want %q", got, tt.want)
+				}
+			}()
+
+			newTestApp(tt.method, tt.path, tt.callback)
+		})
+	}
+}
+
+func benchmarkHandle(b *testing.B, handle httprouter.Handle, method, target, body string, params httprouter.Params) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		handle(httptest.NewRecorder(), r, params)
+	}
+}
+
+func BenchmarkHandleWithoutBody(b *testing.B) {
+	callback := func(id int, name string, q *testQuery) *ResponseBuilder { return new(ResponseBuilder).Ok(nil) }
+	params := httprouter.Params{{Key: "id", Value: "42"}, {Key: "name", Value: "pen"}}
+	target := "/items/42/pen?name=a&limit=10"
+	mn := New()
+	b.Run("plan", func(b *testing.B) {
+		handle := mn.handleWithoutBody(newBindingPlan(callback, "/items/:id/:name", false))
+		benchmarkHandle(b, handle, "GET", target, "", params)
+	})
+
+	b.Run("legacy", func(b *testing.B) {
+		benchmarkHandle(b, mn.legacyHandleWithoutBody(callback), "GET", target, "", params)
+	})
+}
+
+func BenchmarkHandleWithoutBodyPathOnly(b *testing.B) {
+	callback := func(id int) *ResponseBuilder { return new(ResponseBuilder).Ok(nil) }
+	params := httprouter.Params{{Key: "id", Value: "42"}}
+	mn := New()
+	b.Run("plan", func(b *testing.B) {
+		handle := mn.handleWithoutBody(newBindingPlan(callback, "/items/:id", false))
+		benchmarkHandle(b, handle, "GET", "/items/42", "", params)
+	})
+
+	b.Run("legacy", func(b *testing.B) {
+		benchmarkHandle(b, mn.legacyHandleWithoutBody(callback), "GET", "/items/42", "", params)
+	})
+}
+
+func BenchmarkHandleWithBody(b *testing.B) {
+	callback := func(body *testBody) *ResponseBuilder { return new(ResponseBuilder).Ok(nil) }
+	body := `{"name":"john","age":30}`
+	mn := New()
+	b.Run("plan", func(b *testing.B) {
+		handle := mn.handleWithBody(newBindingPlan(callback, "/items", true))
+		benchmarkHandle(b, handle, "POST", "/items", body, nil)
+	})
+
+	b.Run("legacy", func(b *testing.B) {
+		benchmarkHandle(b, mn.legacyHandleWithBody(callback), "POST", "/items", body, nil)
+	})
+}
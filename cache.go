@@ -0,0 +1,369 @@
+package minirest
+
+import (
+	"bytes"
+	"container/list"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// ResponseCache is in-process LRU cache for encoded responses.
+// ResponseCache is safe to use from multiple goroutines, so it can be shared
+// with services for invalidating cached responses after data is changed
+type ResponseCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+	tags     map[string]map[string]struct{}
+}
+
+// CacheOption set options for response cache middleware
+type CacheOption struct {
+	// TTL is time to live of cached response, used when response has no
+	// Cache-Control max-age nor Expires header
+	TTL time.Duration
+	// Vary is request headers that are part of the cache key, such as Accept-Language
+	Vary []string
+}
+
+type cacheEntry struct {
+	key     string
+	status  int
+	header  http.Header
+	body    []byte
+	stored  time.Time
+	expires time.Time
+	tags    []string
+}
+
+// NewResponseCache create ResponseCache that hold up to capacity responses
+func NewResponseCache(capacity int) *ResponseCache {
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	return &ResponseCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+		tags:     make(map[string]map[string]struct{}),
+	}
+}
+
+// Invalidate remove cached responses tagged with any of tags,
+// see ResponseBuilder.CacheTags
+func (c *ResponseCache) Invalidate(tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			if el, ok := c.items[key]; ok {
+				c.removeLocked(el)
+			}
+		}
+	}
+}
+
+// Purge remove all cached responses
+func (c *ResponseCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+	c.tags = make(map[string]map[string]struct{})
+}
+
+// Len return number of cached responses
+func (c *ResponseCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ll.Len()
+}
+
+func (c *ResponseCache) get(key string) *cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+
+	entry := el.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.removeLocked(el)
+		return nil
+	}
+
+	c.ll.MoveToFront(el)
+	return entry
+}
+
+func (c *ResponseCache) set(entry *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[entry.key]; ok {
+		c.removeLocked(el)
+	}
+
+	c.items[entry.key] = c.ll.PushFront(entry)
+	for _, tag := range entry.tags {
+		if c.tags[tag] == nil {
+			c.tags[tag] = make(map[string]struct{})
+		}
+
+		c.tags[tag][entry.key] = struct{}{}
+	}
+
+	for c.ll.Len() > c.capacity {
+		c.removeLocked(c.ll.Back())
+	}
+}
+
+func (c *ResponseCache) removeLocked(el *list.Element) {
+	entry := c.ll.Remove(el).(*cacheEntry)
+	delete(c.items, entry.key)
+	for _, tag := range entry.tags {
+		delete(c.tags[tag], entry.key)
+		if len(c.tags[tag]) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+}
+
+// cacheRecorder record response written by handler while passing it to client
+type cacheRecorder struct {
+	http.ResponseWriter
+	status int
+	// before is header of w before handler is called, header is what handler
+	// added to it. Headers set by middlewares, such as request id, are not recorded
+	before http.Header
+	header http.Header
+	body   bytes.Buffer
+	tags   []string
+}
+
+func (w *cacheRecorder) WriteHeader(code int) {
+	w.record(code)
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *cacheRecorder) Write(b []byte) (int, error) {
+	w.record(http.StatusOK)
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap return the underlying http.ResponseWriter
+func (w *cacheRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// record record status and headers written by handler, once headers are sent
+func (w *cacheRecorder) record(code int) {
+	if w.status != 0 {
+		return
+	}
+
+	w.status = code
+	w.header = headerDiff(w.before, w.Header())
+}
+
+// setCacheTags is called by ResponseBuilder.write
+func (w *cacheRecorder) setCacheTags(tags []string) {
+	w.tags = append(w.tags, tags...)
+}
+
+// cacheTagger is implemented by response writer that accept cache tags
+type cacheTagger interface {
+	setCacheTags(tags []string)
+}
+
+// cacheKey return key of r for endpoint of version. Version is part of the key,
+// as versions selected by header or Accept share the same method and path
+func cacheKey(r *http.Request, version string, vary []string) string {
+	var sb strings.Builder
+	sb.WriteString(version + " " + r.Method + " " + r.URL.Path)
+	// url.Values.Encode sort the queries by key
+	sb.WriteString("?" + r.URL.Query().Encode())
+	for _, h := range vary {
+		sb.WriteString("\n" + http.CanonicalHeaderKey(h) + ": " + strings.Join(r.Header.Values(h), ","))
+	}
+
+	return sb.String()
+}
+
+// cacheDirectives parse Cache-Control header into lowercase directive and its value
+func cacheDirectives(header string) map[string]string {
+	directives := make(map[string]string)
+	for _, d := range strings.Split(header, ",") {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+
+		kv := strings.SplitN(d, "=", 2)
+		key := strings.ToLower(kv[0])
+		if len(kv) == 2 {
+			directives[key] = strings.Trim(kv[1], `"`)
+		} else {
+			directives[key] = ""
+		}
+	}
+
+	return directives
+}
+
+// responseTTL return how long response can be cached, zero means it is not cacheable
+func responseTTL(header http.Header, fallback time.Duration) time.Duration {
+	if header.Get("Set-Cookie") != "" {
+		return 0
+	}
+
+	directives := cacheDirectives(header.Get("Cache-Control"))
+	for _, d := range []string{"no-store", "no-cache", "private"} {
+		if _, ok := directives[d]; ok {
+			return 0
+		}
+	}
+
+	for _, d := range []string{"s-maxage", "max-age"} {
+		if v, ok := directives[d]; ok {
+			sec, err := strconv.Atoi(v)
+			if err != nil {
+				return 0
+			}
+
+			return time.Duration(sec) * time.Second
+		}
+	}
+
+	if expires := header.Get("Expires"); expires != "" {
+		t, err := http.ParseTime(expires)
+		if err != nil {
+			return 0
+		}
+
+		return time.Until(t)
+	}
+
+	return fallback
+}
+
+// cacheHandler serve GET and HEAD requests of endpoint of version from cache, and store cacheable 200 responses
+func cacheHandler(cache *ResponseCache, opt CacheOption, version string, next httprouter.Handle) httprouter.Handle {
+	vary := append([]string(nil), opt.Vary...)
+	sort.Strings(vary)
+
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next(w, r, p)
+			return
+		}
+
+		reqDirectives := cacheDirectives(r.Header.Get("Cache-Control"))
+		if _, ok := reqDirectives["no-store"]; ok {
+			next(w, r, p)
+			return
+		}
+
+		key := cacheKey(r, version, vary)
+		if _, ok := reqDirectives["no-cache"]; !ok {
+			if entry := cache.get(key); entry != nil {
+				writeCached(w, r, entry)
+				return
+			}
+		}
+
+		rec := &cacheRecorder{ResponseWriter: w, before: w.Header().Clone()}
+		next(rec, r, p)
+
+		if rec.status != http.StatusOK {
+			return
+		}
+
+		ttl := responseTTL(w.Header(), opt.TTL)
+		if ttl <= 0 {
+			return
+		}
+
+		now := time.Now()
+		cache.set(&cacheEntry{
+			key:     key,
+			status:  rec.status,
+			header:  rec.header,
+			body:    rec.body.Bytes(),
+			stored:  now,
+			expires: now.Add(ttl),
+			tags:    rec.tags,
+		})
+	}
+}
+
+// headerDiff return header values in after that are not in before.
+// Values appended to existing header are returned without the existing ones
+func headerDiff(before, after http.Header) http.Header {
+	diff := make(http.Header)
+	for k, values := range after {
+		old := before[k]
+		i := 0
+		for i < len(old) && i < len(values) && old[i] == values[i] {
+			i++
+		}
+
+		if i == len(old) {
+			values = values[i:]
+		}
+
+		if len(values) > 0 {
+			diff[k] = append([]string(nil), values...)
+		}
+	}
+
+	return diff
+}
+
+// writeCached write cached response. Headers set by middlewares for this request,
+// such as request id and CORS headers, are kept, cached values are only added to them
+func writeCached(w http.ResponseWriter, r *http.Request, entry *cacheEntry) {
+	header := w.Header()
+	for k, values := range entry.header {
+		for _, v := range values {
+			if !containsValue(header[k], v) {
+				header[k] = append(header[k], v)
+			}
+		}
+	}
+
+	header.Set("Age", strconv.Itoa(int(time.Since(entry.stored).Seconds())))
+
+	lastModified, _ := http.ParseTime(entry.header.Get("Last-Modified"))
+	if notModified(r, entry.status, entry.header.Get("ETag"), lastModified) {
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.WriteHeader(entry.status)
+	if r.Method != http.MethodHead {
+		w.Write(entry.body)
+	}
+}
+
+func containsValue(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,253 @@
+package minirest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestResponseCache(t *testing.T) {
+	cache := NewResponseCache(10)
+	calls := make(map[string]int)
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.Cache(cache, CacheOption{TTL: time.Minute, Vary: []string{"Accept-Language"}})
+		ep.GET("/items/:id", func(id string) *ResponseBuilder {
+			calls[id]++
+			return echo(id).SetHeader("X-Item", id).Vary("Accept-Language")
+		})
+	})
+
+	first := serve(mn, "GET", "/items/1", nil)
+	second := serve(mn, "GET", "/items/1", nil)
+	if calls["1"] != 1 {
+		t.Fatalf("callback is called %d times, want 1", calls["1"])
+	}
+
+	if second.Code != 200 || second.Body.String() != first.Body.String() || second.Header().Get("Age") == "" {
+		t.Errorf("cached status = %d, body %q, Age %q", second.Code, second.Body, second.Header().Get("Age"))
+	}
+
+	for _, key := range []string{"Content-Type", "X-Item", "Vary"} {
+		if got, want := second.Header()[key], first.Header()[key]; !reflect.DeepEqual(got, want) {
+			t.Errorf("cached %s = %q, want %q", key, got, want)
+		}
+	}
+
+	// queries and vary headers are part of the key
+	serve(mn, "GET", "/items/1?a=1", nil)
+	r := httptest.NewRequest("GET", "/items/1", nil)
+	r.Header.Set("Accept-Language", "id")
+	mn.ServeHTTP(httptest.NewRecorder(), r)
+	if calls["1"] != 3 {
+		t.Errorf("callback is called %d times, want 3", calls["1"])
+	}
+}
+
+func TestResponseCacheEviction(t *testing.T) {
+	cache := NewResponseCache(2)
+	calls := make(map[string]int)
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.Cache(cache, CacheOption{TTL: time.Minute})
+		ep.GET("/items/:id", func(id string) *ResponseBuilder {
+			calls[id]++
+			return echo(id)
+		})
+	})
+
+	serve(mn, "GET", "/items/1", nil)
+	serve(mn, "GET", "/items/2", nil)
+	// 1 is used recently, so 2 is evicted
+	serve(mn, "GET", "/items/1", nil)
+	serve(mn, "GET", "/items/3", nil)
+	if cache.Len() != 2 {
+		t.Errorf("Len = %d, want 2", cache.Len())
+	}
+
+	serve(mn, "GET", "/items/1", nil)
+	serve(mn, "GET", "/items/2", nil)
+	if calls["1"] != 1 || calls["2"] != 2 {
+		t.Errorf("calls = %v, want 1 cached and 2 evicted", calls)
+	}
+}
+
+func TestResponseCacheExpiry(t *testing.T) {
+	cache := NewResponseCache(10)
+	calls := make(map[string]int)
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.Cache(cache, CacheOption{TTL: 20 * time.Millisecond})
+		ep.GET("/items/:id", func(id string) *ResponseBuilder {
+			calls[id]++
+			if id == "long" {
+				return echo(id).MaxAge(time.Hour)
+			}
+
+			return echo(id)
+		})
+	})
+
+	serve(mn, "GET", "/items/short", nil)
+	serve(mn, "GET", "/items/long", nil)
+	time.Sleep(30 * time.Millisecond)
+	serve(mn, "GET", "/items/short", nil)
+	serve(mn, "GET", "/items/long", nil)
+	if calls["short"] != 2 || calls["long"] != 1 {
+		t.Errorf("calls = %v, want short expired and long cached by max-age", calls)
+	}
+}
+
+func TestResponseCacheNotStored(t *testing.T) {
+	cache := NewResponseCache(10)
+	calls := make(map[string]int)
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.Cache(cache, CacheOption{TTL: time.Minute})
+		ep.GET("/items/:id", func(id string) *ResponseBuilder {
+			calls[id]++
+			switch id {
+			case "no-store":
+				return echo(id).NoStore()
+			case "private":
+				return echo(id).CacheControl("private")
+			case "cookie":
+				return echo(id).SetCookie("session", "s")
+			case "missing":
+				return new(ResponseBuilder).NotFound("missing")
+			}
+
+			return echo(id)
+		})
+	})
+
+	for _, id := range []string{"no-store", "private", "cookie", "missing"} {
+		serve(mn, "GET", "/items/"+id, nil)
+		serve(mn, "GET", "/items/"+id, nil)
+		if calls[id] != 2 {
+			t.Errorf("response %s is cached", id)
+		}
+	}
+
+	if cache.Len() != 0 {
+		t.Errorf("Len = %d, want 0", cache.Len())
+	}
+
+	// request directives
+	r := httptest.NewRequest("GET", "/items/1", nil)
+	r.Header.Set("Cache-Control", "no-store")
+	mn.ServeHTTP(httptest.NewRecorder(), r)
+	if cache.Len() != 0 {
+		t.Error("response of no-store request is cached")
+	}
+
+	serve(mn, "GET", "/items/1", nil)
+	r.Header.Set("Cache-Control", "no-cache")
+	mn.ServeHTTP(httptest.NewRecorder(), r)
+	if calls["1"] != 3 {
+		t.Errorf("callback is called %d times, want no-cache request to skip the cache", calls["1"])
+	}
+}
+
+func TestResponseCacheInvalidate(t *testing.T) {
+	cache := NewResponseCache(10)
+	calls := make(map[string]int)
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.Cache(cache, CacheOption{TTL: time.Minute})
+		ep.GET("/items/:id", func(id string) *ResponseBuilder {
+			calls[id]++
+			return echo(id).CacheTags("items", "item:"+id)
+		})
+	})
+
+	serve(mn, "GET", "/items/1", nil)
+	serve(mn, "GET", "/items/2", nil)
+	cache.Invalidate("item:1")
+	serve(mn, "GET", "/items/1", nil)
+	serve(mn, "GET", "/items/2", nil)
+	if calls["1"] != 2 || calls["2"] != 1 {
+		t.Errorf("calls = %v, want only 1 invalidated", calls)
+	}
+
+	cache.Invalidate("items")
+	if cache.Len() != 0 {
+		t.Errorf("Len = %d, want 0", cache.Len())
+	}
+
+	serve(mn, "GET", "/items/1", nil)
+	cache.Purge()
+	if cache.Len() != 0 {
+		t.Errorf("Len after Purge = %d", cache.Len())
+	}
+}
+
+func TestResponseCacheConditional(t *testing.T) {
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.Cache(NewResponseCache(10), CacheOption{TTL: time.Minute})
+		ep.GET("/items/:id", func(id string) *ResponseBuilder {
+			return echo(id).ETag("v1")
+		})
+	})
+
+	serve(mn, "GET", "/items/1", nil)
+	r := httptest.NewRequest("GET", "/items/1", nil)
+	r.Header.Set("If-None-Match", `"v1"`)
+	w := httptest.NewRecorder()
+	mn.ServeHTTP(w, r)
+	if w.Code != 304 || w.Body.Len() != 0 || w.Header().Get("ETag") != `"v1"` {
+		t.Errorf("status = %d, body %q, ETag %q", w.Code, w.Body, w.Header().Get("ETag"))
+	}
+}
+
+// headers set by middlewares for each request must not be replayed from cache
+func TestResponseCacheMiddlewareHeaders(t *testing.T) {
+	calls := make(map[string]int)
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.Cache(NewResponseCache(10), CacheOption{TTL: time.Minute})
+		ep.GET("/items/:id", func(id string) *ResponseBuilder {
+			calls[id]++
+			return echo(id).AddHeader("Vary", "Accept")
+		})
+	})
+
+	mn.AccessLog(AccessLogOption{Output: io.Discard})
+	mn.CORS(CORSOption{AllowOrigins: []string{"https://a.example", "https://b.example"}})
+
+	get := func(origin string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/items/1", nil)
+		r.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		mn.ServeHTTP(w, r)
+
+		return w
+	}
+
+	first, second := get("https://a.example"), get("https://b.example")
+	if calls["1"] != 1 {
+		t.Fatalf("callback is called %d times, want 1", calls["1"])
+	}
+
+	if id1, id2 := first.Header().Get("X-Request-ID"), second.Header().Get("X-Request-ID"); id1 == "" || id1 == id2 {
+		t.Errorf("X-Request-ID = %q and %q, want different ids", id1, id2)
+	}
+
+	if got := second.Header()["X-Request-Id"]; len(got) != 1 {
+		t.Errorf("X-Request-ID = %q, want single value", got)
+	}
+
+	if got := second.Header().Get("Access-Control-Allow-Origin"); got != "https://b.example" {
+		t.Errorf("Access-Control-Allow-Origin = %q", got)
+	}
+
+	if got, want := second.Header()["Vary"], first.Header()["Vary"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Vary = %q, want %q", got, want)
+	}
+}
+
+func TestHeaderDiff(t *testing.T) {
+	before := http.Header{"A": {"1"}, "B": {"1"}, "C": {"1"}}
+	after := http.Header{"A": {"1"}, "B": {"1", "2"}, "C": {"3"}, "D": {"4"}}
+	want := http.Header{"B": {"2"}, "C": {"3"}, "D": {"4"}}
+	if got := headerDiff(before, after); !reflect.DeepEqual(got, want) {
+		t.Errorf("headerDiff = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,785 @@
+// Package clientgen generate typed Go HTTP client from OpenAPI document generated by minirest.
+//
+// Each operation with JSON response become a method of Client, with typed path parameters,
+// query parameters and request body, and it decode the response into typed result.
+// The envelope of the app (default, raw, JSON:API or problem details) is detected from
+// the response schemas of the document, so responses are unwrapped the way the app wrote them.
+// Error responses are returned as *Error.
+// Use Minirest.OpenAPIDocument to generate client directly from registered controllers
+package clientgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/tamboto2000/minirest/openapi"
+)
+
+// Options is options for generating client
+type Options struct {
+	// Package is package name of generated file, default is "client"
+	Package string
+}
+
+// Generate generate source of client package from doc
+func Generate(doc *openapi.Document, opt Options) ([]byte, error) {
+	if opt.Package == "" {
+		opt.Package = "client"
+	}
+
+	gen := &generator{doc: doc, types: make(map[string]string)}
+	gen.success, gen.errors = detectEnvelopes(doc)
+	gen.printf("%s\n", runtime)
+	accept := mediaTypes[gen.success]
+	if mediaTypes[gen.errors] != accept {
+		accept += ", " + mediaTypes[gen.errors]
+	}
+
+	gen.printf("const accept = %q\n\n", accept)
+	gen.printf("%s\n", successDecoders[gen.success])
+	gen.printf("%s\n", errorDecoders[gen.errors])
+	if gen.success == envelopeDefault || gen.errors == envelopeDefault {
+		gen.printf("%s\n", envelopeType)
+	}
+
+	if doc.Components != nil {
+		names := make([]string, 0, len(doc.Components.Schemas))
+		for name := range doc.Components.Schemas {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+		for _, name := range names {
+			gen.types[name] = goName(name)
+		}
+
+		for _, name := range names {
+			gen.printf("// %s is schema %s\n", gen.types[name], name)
+			gen.printf("type %s %s\n\n", gen.types[name], gen.goType(doc.Components.Schemas[name]))
+		}
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+	for _, path := range paths {
+		item := doc.Paths[path]
+		for _, method := range openapi.Methods {
+			if op := item.Operation(method); op != nil {
+				if err := gen.operation(method, path, op); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	body := gen.buf.String()
+	imports := []string{"bytes", "context", "encoding/json", "fmt", "io", "net/http", "net/url", "reflect", "strings"}
+	if strings.Contains(body, "time.Time") {
+		imports = append(imports, "time")
+	}
+
+	var head strings.Builder
+	head.WriteString("// Code generated by minirest clientgen. DO NOT EDIT.\n\n")
+	title := strings.TrimSpace(doc.Info.Title + " " + doc.Info.Version)
+	if title == "" {
+		title = "minirest API"
+	}
+
+	head.WriteString("// Package " + opt.Package + " is client of " + title + "\n")
+	head.WriteString("package " + opt.Package + "\n\nimport (\n")
+	for _, path := range imports {
+		head.WriteString("\t" + strconv.Quote(path) + "\n")
+	}
+
+	head.WriteString(")\n\n")
+	src, err := format.Source([]byte(head.String() + body))
+	if err != nil {
+		return nil, fmt.Errorf("clientgen: format generated source: %v", err)
+	}
+
+	return src, nil
+}
+
+type generator struct {
+	doc   *openapi.Document
+	buf   bytes.Buffer
+	types map[string]string
+	// envelopes of successful and error responses
+	success, errors int
+}
+
+func (gen *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&gen.buf, format, args...)
+}
+
+// operation generate method of operation.
+// Operations without JSON response, such as SSE and WebSocket, are skipped
+func (gen *generator) operation(method, path string, op *openapi.Operation) error {
+	name := goName(op.OperationID)
+	if name == "" {
+		return fmt.Errorf("clientgen: %s %s has no operationId", method, path)
+	}
+
+	success, ok := successResponse(op, gen.success)
+	if !ok {
+		gen.printf("// %s %s %s is skipped, its response is not JSON\n\n", name, method, path)
+		return nil
+	}
+
+	var args, query []string
+	var pathParams, headerParams []*openapi.Parameter
+	var queryParams []*openapi.Parameter
+	for _, param := range op.Parameters {
+		switch param.In {
+		case openapi.InPath:
+			pathParams = append(pathParams, param)
+		case openapi.InQuery:
+			queryParams = append(queryParams, param)
+		case openapi.InHeader:
+			headerParams = append(headerParams, param)
+		}
+	}
+
+	for _, param := range pathParams {
+		args = append(args, argName(param.Name)+" "+gen.goType(param.Schema))
+	}
+
+	if len(queryParams) > 0 {
+		gen.printf("// %sParams is query parameters of %s\n", name, name)
+		gen.printf("type %sParams struct {\n", name)
+		for _, param := range queryParams {
+			field := goName(param.Name)
+			gen.printf("\t%s %s\n", field, gen.goType(param.Schema))
+			query = append(query, fmt.Sprintf("setQuery(query, %q, params.%s, %t)", param.Name, field, param.Required))
+		}
+
+		gen.printf("}\n\n")
+		args = append(args, "params *"+name+"Params")
+	}
+
+	for _, param := range headerParams {
+		args = append(args, argName(param.Name)+" string")
+	}
+
+	body := "nil"
+	if op.RequestBody != nil {
+		if media, ok := op.RequestBody.Content["application/json"]; ok {
+			args = append(args, "body "+gen.goType(media.Schema))
+			body = "body"
+		}
+	}
+
+	result := ""
+	if success != nil {
+		result = gen.goType(success)
+	}
+
+	summary := op.Summary
+	if summary == "" {
+		summary = "call"
+	}
+
+	gen.printf("// %s %s: %s %s\n", name, lowerFirst(summary), method, path)
+	if op.Deprecated {
+		gen.printf("//\n// Deprecated: %s %s is deprecated\n", method, path)
+	}
+
+	gen.printf("func (c *Client) %s(%s) ", name, strings.Join(append([]string{"ctx context.Context"}, args...), ", "))
+	if result != "" {
+		gen.printf("(%s, error) {\n", result)
+	} else {
+		gen.printf("error {\n")
+	}
+
+	gen.printf("\tpath := %s\n", pathExpr(path))
+	gen.printf("\tquery := url.Values{}\n")
+	if len(query) > 0 {
+		gen.printf("\tif params != nil {\n")
+		for _, line := range query {
+			gen.printf("\t\t%s\n", line)
+		}
+
+		gen.printf("\t}\n")
+	}
+
+	gen.printf("\theader := http.Header{}\n")
+	for _, param := range headerParams {
+		gen.printf("\tif %s != \"\" {\n\t\theader.Set(%q, %s)\n\t}\n", argName(param.Name), param.Name, argName(param.Name))
+	}
+
+	if result != "" {
+		gen.printf("\tvar out %s\n", result)
+		gen.printf("\terr := c.do(ctx, %q, path, query, header, %s, &out)\n", method, body)
+		gen.printf("\treturn out, err\n}\n\n")
+	} else {
+		gen.printf("\treturn c.do(ctx, %q, path, query, header, %s, nil)\n}\n\n", method, body)
+	}
+
+	return nil
+}
+
+// successResponse return schema of data in the first 2xx response written by envelope,
+// ok is false when the response is not JSON
+func successResponse(op *openapi.Operation, envelope int) (body *openapi.Schema, ok bool) {
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		codes = append(codes, code)
+	}
+
+	sort.Strings(codes)
+	for _, code := range codes {
+		status, err := strconv.Atoi(code)
+		if err != nil || status < 200 || status > 299 {
+			continue
+		}
+
+		resp := op.Responses[code]
+		if len(resp.Content) == 0 {
+			return nil, status == http.StatusNoContent
+		}
+
+		media, ok := jsonContent(resp)
+		if !ok {
+			return nil, false
+		}
+
+		if media.Schema == nil {
+			return nil, true
+		}
+
+		switch envelope {
+		case envelopeRaw:
+			return media.Schema, true
+		case envelopeJSONAPI:
+			return media.Schema.Properties["data"], true
+		}
+
+		return media.Schema.Properties["body"], true
+	}
+
+	return nil, false
+}
+
+// envelopes of responses, detected from the document
+const (
+	envelopeDefault = iota
+	envelopeRaw
+	envelopeJSONAPI
+	envelopeProblem
+)
+
+// detectEnvelopes return envelopes of successful and error responses described in doc,
+// default envelope is returned when there is no response with content
+func detectEnvelopes(doc *openapi.Document) (success, errors int) {
+	success, errors = -1, -1
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+	for _, path := range paths {
+		for _, method := range openapi.Methods {
+			op := doc.Paths[path].Operation(method)
+			if op == nil {
+				continue
+			}
+
+			for code, resp := range op.Responses {
+				status, err := strconv.Atoi(code)
+				if err != nil || len(resp.Content) == 0 {
+					continue
+				}
+
+				envelope, ok := responseEnvelope(resp)
+				if !ok {
+					continue
+				}
+
+				if status < 300 && success == -1 && envelope != envelopeProblem {
+					success = envelope
+				} else if status >= 400 && errors == -1 {
+					errors = envelope
+				}
+			}
+		}
+	}
+
+	if success == -1 {
+		success = envelopeDefault
+	}
+
+	if errors == -1 {
+		errors = envelopeDefault
+	}
+
+	return success, errors
+}
+
+// responseEnvelope return envelope writing resp, ok is false when resp is not JSON
+func responseEnvelope(resp *openapi.Response) (int, bool) {
+	if _, ok := resp.Content["application/problem+json"]; ok {
+		return envelopeProblem, true
+	}
+
+	if _, ok := resp.Content["application/vnd.api+json"]; ok {
+		return envelopeJSONAPI, true
+	}
+
+	media, ok := resp.Content["application/json"]
+	if !ok {
+		return 0, false
+	}
+
+	if schema := media.Schema; schema != nil {
+		if schema.Ref == openapi.RefPrefix+"Response" ||
+			schema.Properties["statusCode"] != nil && schema.Properties["status"] != nil {
+			return envelopeDefault, true
+		}
+	}
+
+	return envelopeRaw, true
+}
+
+// jsonContent return JSON content of resp
+func jsonContent(resp *openapi.Response) (openapi.MediaType, bool) {
+	for _, contentType := range []string{"application/json", "application/vnd.api+json", "application/problem+json"} {
+		if media, ok := resp.Content[contentType]; ok {
+			return media, true
+		}
+	}
+
+	return openapi.MediaType{}, false
+}
+
+// goType return Go type of schema. Object with properties become struct
+func (gen *generator) goType(schema *openapi.Schema) string {
+	if schema == nil {
+		return "json.RawMessage"
+	}
+
+	if schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, openapi.RefPrefix)
+		if typ, ok := gen.types[name]; ok {
+			return typ
+		}
+
+		return "json.RawMessage"
+	}
+
+	switch schema.Type {
+	case "boolean":
+		return "bool"
+	case "integer":
+		if schema.Format == "int64" {
+			return "int64"
+		}
+
+		return "int"
+	case "number":
+		if schema.Format == "float" {
+			return "float32"
+		}
+
+		return "float64"
+	case "string":
+		switch schema.Format {
+		case "date-time":
+			return "time.Time"
+		case "byte":
+			return "[]byte"
+		}
+
+		return "string"
+	case "array":
+		return "[]" + gen.goType(schema.Items)
+	case "object":
+		if len(schema.Properties) == 0 {
+			if schema.AdditionalProperties != nil {
+				return "map[string]" + gen.goType(schema.AdditionalProperties)
+			}
+
+			return "map[string]json.RawMessage"
+		}
+
+		return gen.structType(schema)
+	}
+
+	return "json.RawMessage"
+}
+
+// structType return struct type of object schema.
+// Optional fields referring to other schemas are pointer, so recursive schemas are valid
+func (gen *generator) structType(schema *openapi.Schema) string {
+	required := make(map[string]bool)
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	var sb strings.Builder
+	sb.WriteString("struct {\n")
+	for _, name := range names {
+		prop := schema.Properties[name]
+		typ := gen.goType(prop)
+		tag := name
+		if !required[name] {
+			tag += ",omitempty"
+			if prop.Ref != "" {
+				typ = "*" + typ
+			}
+		}
+
+		sb.WriteString(fmt.Sprintf("\t%s %s `json:%q`\n", goName(name), typ, tag))
+	}
+
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// pathExpr return Go expression building path, such as "/users/" + url.PathEscape(fmtParam(id))
+func pathExpr(path string) string {
+	var parts []string
+	rest := path
+	for {
+		start := strings.Index(rest, "{")
+		end := strings.Index(rest, "}")
+		if start == -1 || end < start {
+			break
+		}
+
+		if start > 0 {
+			parts = append(parts, strconv.Quote(rest[:start]))
+		}
+
+		parts = append(parts, "url.PathEscape(fmtParam("+argName(rest[start+1:end])+"))")
+		rest = rest[end+1:]
+	}
+
+	if rest != "" || len(parts) == 0 {
+		parts = append(parts, strconv.Quote(rest))
+	}
+
+	return strings.Join(parts, " + ")
+}
+
+// common initialisms kept uppercase in Go names
+var initialisms = map[string]bool{
+	"ID": true, "URL": true, "URI": true, "HTTP": true, "API": true,
+	"JSON": true, "UUID": true, "IP": true, "SQL": true, "HTML": true,
+}
+
+// goName return exported Go name of name, such as UserID for user_id
+func goName(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var sb strings.Builder
+	for _, word := range words {
+		if upper := strings.ToUpper(word); initialisms[upper] {
+			sb.WriteString(upper)
+			continue
+		}
+
+		sb.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+
+	result := sb.String()
+	if result != "" && unicode.IsDigit(rune(result[0])) {
+		result = "X" + result
+	}
+
+	return result
+}
+
+// Go keywords that can't be used as argument names
+var keywords = map[string]bool{
+	"break": true, "case": true, "chan": true, "const": true, "continue": true, "default": true,
+	"defer": true, "else": true, "fallthrough": true, "for": true, "func": true, "go": true,
+	"goto": true, "if": true, "import": true, "interface": true, "map": true, "package": true,
+	"range": true, "return": true, "select": true, "struct": true, "switch": true, "type": true, "var": true,
+	// names used in generated methods
+	"ctx": true, "body": true, "params": true, "path": true, "query": true, "header": true, "out": true, "err": true,
+}
+
+// argName return unexported Go name of name
+func argName(name string) string {
+	name = lowerFirst(goName(name))
+	if upper := strings.ToUpper(name); initialisms[upper] {
+		name = strings.ToLower(name)
+	}
+
+	if keywords[name] {
+		name += "Arg"
+	}
+
+	return name
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// runtime is types and helpers included in every generated client
+const runtime = `// Client is HTTP client of the API
+type Client struct {
+	// BaseURL is URL of the server, such as http://localhost:8080
+	BaseURL string
+	// HTTPClient is client used to send requests, default is http.DefaultClient
+	HTTPClient *http.Client
+	// Header is headers sent with every request, such as Authorization
+	Header http.Header
+}
+
+// NewClient create Client for server at baseURL
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), Header: http.Header{}}
+}
+
+// Error is error response returned by server
+type Error struct {
+	StatusCode  int
+	Status      string
+	Description string
+	// Body is data of error response, such as validation details
+	Body json.RawMessage
+}
+
+// Error return description of error response
+func (e *Error) Error() string {
+	if e.Description != "" {
+		return e.Status + ": " + e.Description
+	}
+
+	return e.Status
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, header http.Header, body, out interface{}) error {
+	var reader *bytes.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		reader = bytes.NewReader(raw)
+	}
+
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return err
+	}
+
+	if reader != nil {
+		req, err = http.NewRequestWithContext(ctx, method, u, reader)
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	for key, values := range c.Header {
+		req.Header[key] = values
+	}
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	req.Header.Set("Accept", accept)
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		e := decodeError(resp.StatusCode, raw)
+		if e.Status == "" {
+			e.Status = http.StatusText(resp.StatusCode)
+		}
+
+		return e
+	}
+
+	if out == nil || len(raw) == 0 {
+		return nil
+	}
+
+	return decodeSuccess(raw, out)
+}
+
+// fmtParam format path parameter
+func fmtParam(v interface{}) string {
+	return fmt.Sprint(v)
+}
+
+// setQuery set query parameter key, zero value is skipped unless required.
+// Slice is sent as repeated parameter
+func setQuery(query url.Values, key string, v interface{}, required bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice {
+		for i := 0; i < rv.Len(); i++ {
+			query.Add(key, fmt.Sprint(rv.Index(i).Interface()))
+		}
+
+		return
+	}
+
+	if !required && rv.IsZero() {
+		return
+	}
+
+	query.Set(key, fmt.Sprint(v))
+}
+`
+
+// envelopeType is body written by the default envelope
+const envelopeType = `type envelope struct {
+	StatusCode  int             ` + "`json:\"statusCode\"`" + `
+	Status      string          ` + "`json:\"status\"`" + `
+	Description string          ` + "`json:\"description\"`" + `
+	Body        json.RawMessage ` + "`json:\"body\"`" + `
+}
+`
+
+// mediaTypes is content type of body written by envelope, sent in Accept header
+var mediaTypes = map[int]string{
+	envelopeDefault: "application/json",
+	envelopeRaw:     "application/json",
+	envelopeJSONAPI: "application/vnd.api+json",
+	envelopeProblem: "application/problem+json",
+}
+
+// successDecoders is decodeSuccess of generated client, by envelope of successful responses
+var successDecoders = map[int]string{
+	envelopeDefault: `// decodeSuccess decode body of Response envelope into out
+func decodeSuccess(raw []byte, out interface{}) error {
+	var env envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return err
+	}
+
+	if len(env.Body) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(env.Body, out)
+}
+`,
+	envelopeRaw: `// decodeSuccess decode response body into out
+func decodeSuccess(raw []byte, out interface{}) error {
+	return json.Unmarshal(raw, out)
+}
+`,
+	envelopeJSONAPI: `// decodeSuccess decode data of JSON:API document into out
+func decodeSuccess(raw []byte, out interface{}) error {
+	var doc struct {
+		Data json.RawMessage ` + "`json:\"data\"`" + `
+	}
+
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return err
+	}
+
+	if len(doc.Data) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(doc.Data, out)
+}
+`,
+}
+
+// errorDecoders is decodeError of generated client, by envelope of error responses.
+// Body that can't be decoded leave Error without status and description
+var errorDecoders = map[int]string{
+	envelopeDefault: `// decodeError decode Response envelope of error response
+func decodeError(code int, raw []byte) *Error {
+	var env envelope
+	json.Unmarshal(raw, &env)
+	return &Error{StatusCode: code, Status: env.Status, Description: env.Description, Body: env.Body}
+}
+`,
+	envelopeRaw: `// decodeError decode error response, which is either description or data
+func decodeError(code int, raw []byte) *Error {
+	e := &Error{StatusCode: code}
+	var desc string
+	if err := json.Unmarshal(raw, &desc); err == nil {
+		e.Description = desc
+	} else if len(raw) > 0 {
+		e.Body = bytes.TrimSpace(raw)
+	}
+
+	return e
+}
+`,
+	envelopeJSONAPI: `// decodeError decode the first error of JSON:API error document
+func decodeError(code int, raw []byte) *Error {
+	var doc struct {
+		Errors []struct {
+			Code   string          ` + "`json:\"code\"`" + `
+			Detail string          ` + "`json:\"detail\"`" + `
+			Meta   json.RawMessage ` + "`json:\"meta\"`" + `
+		} ` + "`json:\"errors\"`" + `
+	}
+
+	e := &Error{StatusCode: code}
+	if err := json.Unmarshal(raw, &doc); err == nil && len(doc.Errors) > 0 {
+		e.Status = doc.Errors[0].Code
+		e.Description = doc.Errors[0].Detail
+		e.Body = doc.Errors[0].Meta
+	}
+
+	return e
+}
+`,
+	envelopeProblem: `// decodeError decode RFC 7807 problem details
+func decodeError(code int, raw []byte) *Error {
+	var problem struct {
+		Title   string          ` + "`json:\"title\"`" + `
+		Detail  string          ` + "`json:\"detail\"`" + `
+		Details json.RawMessage ` + "`json:\"details\"`" + `
+	}
+
+	json.Unmarshal(raw, &problem)
+	return &Error{StatusCode: code, Status: problem.Title, Description: problem.Detail, Body: problem.Details}
+}
+`,
+}
@@ -0,0 +1,271 @@
+package clientgen
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tamboto2000/minirest"
+)
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type userFilter struct {
+	Name string `schema:"name"`
+}
+
+type userController struct{}
+
+func (ctrl *userController) List(filter userFilter) *minirest.ResponseBuilder {
+	return new(minirest.ResponseBuilder).Ok([]user{{ID: 1, Name: filter.Name}})
+}
+
+func (ctrl *userController) Get(id int) *minirest.ResponseBuilder {
+	if id == http.StatusNotFound {
+		return new(minirest.ResponseBuilder).NotFound("user 404 not found")
+	}
+
+	return new(minirest.ResponseBuilder).Ok(user{ID: id, Name: "John"})
+}
+
+func (ctrl *userController) Create(u user) *minirest.ResponseBuilder {
+	if u.Name == "" {
+		return new(minirest.ResponseBuilder).UnprocessableEntity("invalid user", map[string]string{"name": "required"})
+	}
+
+	u.ID = 2
+	return new(minirest.ResponseBuilder).Created("/users/2", u)
+}
+
+func (ctrl *userController) Delete(id int) *minirest.ResponseBuilder {
+	return new(minirest.ResponseBuilder).NoContent("")
+}
+
+func (ctrl *userController) Endpoints() *minirest.Endpoints {
+	ep := new(minirest.Endpoints)
+	ep.BasePath("/users")
+	ep.GET("", ctrl.List)
+	ep.Doc("GET", "", minirest.EndpointDoc{Response: []user{}})
+	ep.GET("/:id", ctrl.Get)
+	ep.Doc("GET", "/:id", minirest.EndpointDoc{Response: user{}, Errors: []int{http.StatusNotFound}})
+	ep.POST("", ctrl.Create)
+	ep.Doc("POST", "", minirest.EndpointDoc{Response: user{}, Status: http.StatusCreated, Errors: []int{http.StatusUnprocessableEntity}})
+	ep.DELETE("/:id", ctrl.Delete)
+	ep.Doc("DELETE", "/:id", minirest.EndpointDoc{Status: http.StatusNoContent})
+
+	return ep
+}
+
+// clientTest is test of generated client, run against the app at CLIENT_URL.
+// Formatted with description of 422 error, which raw envelope doesn't write
+const clientTest = `package client
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	c := NewClient(os.Getenv("CLIENT_URL"))
+	ctx := context.Background()
+	u, err := c.UserControllerGet(ctx, 7)
+	if err != nil || u.ID != 7 || u.Name != "John" {
+		t.Errorf("Get = %%+v, %%v", u, err)
+	}
+
+	users, err := c.UserControllerList(ctx, &UserControllerListParams{Name: "a"})
+	if err != nil || len(users) != 1 || users[0].Name != "a" {
+		t.Errorf("List = %%+v, %%v", users, err)
+	}
+
+	u, err = c.UserControllerCreate(ctx, User{Name: "b"})
+	if err != nil || u.ID != 2 || u.Name != "b" {
+		t.Errorf("Create = %%+v, %%v", u, err)
+	}
+
+	if err := c.UserControllerDelete(ctx, 1); err != nil {
+		t.Errorf("Delete = %%v", err)
+	}
+
+	var e *Error
+	_, err = c.UserControllerGet(ctx, 404)
+	if !errors.As(err, &e) || e.StatusCode != 404 || e.Description != "user 404 not found" || e.Status == "" {
+		t.Errorf("Get missing user = %%#v", err)
+	}
+
+	_, err = c.UserControllerCreate(ctx, User{})
+	if !errors.As(err, &e) || e.StatusCode != 422 || e.Description != %q || string(e.Body) != ` + "`" + `{"name":"required"}` + "`" + ` {
+		t.Errorf("Create invalid user = %%#v", err)
+	}
+}
+`
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       minirest.Envelope
+		accept    string
+		errorDesc string
+	}{
+		{name: "default", accept: "application/json", errorDesc: "invalid user"},
+		{name: "raw", env: minirest.RawEnvelope, accept: "application/json"},
+		{name: "jsonapi", env: minirest.JSONAPIEnvelope, accept: "application/vnd.api+json", errorDesc: "invalid user"},
+		{name: "problem", env: minirest.ProblemEnvelope(nil), accept: "application/json, application/problem+json", errorDesc: "invalid user"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mn := minirest.New()
+			mn.Logger = minirest.NopLogger
+			mn.AddController(new(userController))
+			if tt.env != nil {
+				mn.ResponseEnvelope(tt.env)
+			}
+
+			src, err := Generate(mn.OpenAPIDocument(minirest.OpenAPIOption{Title: "Users"}), Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, want := range []string{
+				"package client",
+				fmt.Sprintf("const accept = %q", tt.accept),
+				"func (c *Client) UserControllerGet(ctx context.Context, id int64) (User, error)",
+				"func (c *Client) UserControllerList(ctx context.Context, params *UserControllerListParams) ([]User, error)",
+				"func (c *Client) UserControllerCreate(ctx context.Context, body User) (User, error)",
+				"func (c *Client) UserControllerDelete(ctx context.Context, id int64) error",
+			} {
+				if !strings.Contains(string(src), want) {
+					t.Errorf("generated client doesn't contain %q", want)
+				}
+			}
+
+			if testing.Short() {
+				return
+			}
+
+			srv := httptest.NewServer(mn)
+			defer srv.Close()
+			runClientTest(t, src, fmt.Sprintf(clientTest, tt.errorDesc), srv.URL)
+		})
+	}
+}
+
+// runClientTest run test of generated client src with go test, in directory inside the module
+func runClientTest(t *testing.T, src []byte, test, url string) {
+	t.Helper()
+	if err := os.MkdirAll("testdata", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := os.MkdirTemp("testdata", "client")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, "client.go"), src, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "client_test.go"), []byte(test), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("go", "test", "-count=1", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "CLIENT_URL="+url)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("test of generated client failed: %v\n%s", err, out)
+	}
+}
+
+// writeApp write main package with source src in directory inside the module, and return its package path
+func writeApp(t *testing.T, src string) string {
+	t.Helper()
+	if err := os.MkdirAll("testdata", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := os.MkdirTemp("testdata", "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return "./" + filepath.ToSlash(dir)
+}
+
+func TestLoadApp(t *testing.T) {
+	if testing.Short() {
+		t.Skip("build app with go build")
+	}
+
+	pkg := writeApp(t, `package main
+
+import "github.com/tamboto2000/minirest"
+
+type ctrl struct{}
+
+func (ctrl) Get() *minirest.ResponseBuilder { return new(minirest.ResponseBuilder).Ok("ok") }
+
+func (c ctrl) Endpoints() *minirest.Endpoints {
+	ep := new(minirest.Endpoints)
+	ep.GET("/health", c.Get)
+	return ep
+}
+
+func main() {
+	mn := minirest.New()
+	mn.AddController(ctrl{})
+	mn.OpenAPI(minirest.OpenAPIOption{Title: "Health"})
+	mn.RunServer()
+}
+`)
+
+	doc, err := LoadApp(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Info.Title != "Health" || doc.Paths["/health"] == nil {
+		t.Errorf("document = %+v", doc)
+	}
+}
+
+func TestLoadAppTimeout(t *testing.T) {
+	if testing.Short() {
+		t.Skip("build app with go build")
+	}
+
+	// app that never call RunServer
+	pkg := writeApp(t, "package main\n\nimport \"time\"\n\nfunc main() { time.Sleep(time.Hour) }\n")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err := LoadAppContext(ctx, pkg)
+	if err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Errorf("err = %v, want deadline exceeded", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("LoadAppContext returned after %v", elapsed)
+	}
+}
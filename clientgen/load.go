@@ -0,0 +1,111 @@
+package clientgen
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	goruntime "runtime"
+	"strings"
+	"time"
+
+	"github.com/tamboto2000/minirest"
+	"github.com/tamboto2000/minirest/openapi"
+)
+
+// LoadSpec read OpenAPI document from file or http(s) URL
+func LoadSpec(source string) (*openapi.Document, error) {
+	var raw []byte
+	var err error
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		raw, err = fetch(source)
+	} else {
+		raw, err = os.ReadFile(source)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return decode(raw)
+}
+
+// LoadTimeout is time limit of building and running app in LoadApp
+var LoadTimeout = 2 * time.Minute
+
+// LoadApp read OpenAPI document of minirest app in package pkg, within LoadTimeout
+func LoadApp(pkg string) (*openapi.Document, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), LoadTimeout)
+	defer cancel()
+
+	return LoadAppContext(ctx, pkg)
+}
+
+// LoadAppContext read OpenAPI document of minirest app in package pkg.
+// The app is built with go build and run with minirest.OpenAPIEnv set,
+// so RunServer write the document instead of serving it.
+// The build or the app is killed when ctx is done, such as when the app doesn't call RunServer
+func LoadAppContext(ctx context.Context, pkg string) (*openapi.Document, error) {
+	dir, err := os.MkdirTemp("", "clientgen")
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.RemoveAll(dir)
+	bin := filepath.Join(dir, "app")
+	if goruntime.GOOS == "windows" {
+		bin += ".exe"
+	}
+
+	build := exec.CommandContext(ctx, "go", "build", "-o", bin, pkg)
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		return nil, fmt.Errorf("build %s: %v", pkg, contextErr(ctx, err))
+	}
+
+	cmd := exec.CommandContext(ctx, bin)
+	cmd.Env = append(os.Environ(), minirest.OpenAPIEnv+"=1")
+	cmd.Stderr = os.Stderr
+	raw, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("run %s: %v", pkg, contextErr(ctx, err))
+	}
+
+	return decode(raw)
+}
+
+// contextErr return error of ctx if it is done, so killed command is reported as timeout
+func contextErr(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+
+	return err
+}
+
+func fetch(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get %s: %s", url, resp.Status)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+func decode(raw []byte) (*openapi.Document, error) {
+	doc := new(openapi.Document)
+	if err := json.Unmarshal(raw, doc); err != nil {
+		return nil, fmt.Errorf("decode OpenAPI document: %v", err)
+	}
+
+	return doc, nil
+}
@@ -0,0 +1,22 @@
+// Command clientgen generate typed Go client of minirest app.
+//
+// The OpenAPI document is read from file or URL given by -spec, or from the app given by -app,
+// which is run with MINIREST_OPENAPI set so RunServer write the document instead of serving it.
+// Example:
+//
+//	//go:generate go run github.com/tamboto2000/minirest/cmd/clientgen -app ./cmd/server -pkg userapi -o userapi/client.go
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/tamboto2000/minirest/internal/clientcmd"
+)
+
+func main() {
+	if err := clientcmd.Run("clientgen", os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "clientgen:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/tamboto2000/minirest/internal/clientcmd"
+)
+
+const controllerTemplate = `package {{.Package}}
+
+import (
+	"github.com/tamboto2000/minirest"
+)
+
+// {{.Type}} handle {{.Name}} endpoints
+type {{.Type}} struct{}
+
+// List return all {{.Name}}
+func (ctrl *{{.Type}}) List() *minirest.ResponseBuilder {
+	return new(minirest.ResponseBuilder).Ok([]interface{}{})
+}
+
+// Get return {{.Name}} with id
+func (ctrl *{{.Type}}) Get(id int) *minirest.ResponseBuilder {
+	return new(minirest.ResponseBuilder).NotFound("")
+}
+
+// Create create {{.Name}}
+func (ctrl *{{.Type}}) Create(body map[string]interface{}) *minirest.ResponseBuilder {
+	return new(minirest.ResponseBuilder).Created("", body)
+}
+
+// Endpoints register endpoints of {{.Type}}
+func (ctrl *{{.Type}}) Endpoints() *minirest.Endpoints {
+	ep := new(minirest.Endpoints)
+	ep.BasePath("/{{.Path}}")
+	ep.GET("", ctrl.List)
+	ep.GET("/:id", ctrl.Get)
+	ep.POST("", ctrl.Create)
+
+	return ep
+}
+`
+
+const serviceTemplate = `package {{.Package}}
+
+// {{.Type}} is service of {{.Name}}
+type {{.Type}} struct{}
+
+// Init initialize {{.Type}}
+func (svc *{{.Type}}) Init() {}
+`
+
+type genData struct {
+	Package string
+	Name    string
+	Type    string
+	Path    string
+}
+
+var (
+	runServerRe     = regexp.MustCompile(`(?m)^([ \t]*)(\w+)\.RunServer\(\)`)
+	addControllerRe = regexp.MustCompile(`(?m)^([ \t]*)(\w+)\.AddController\(.*$`)
+	addServiceRe    = regexp.MustCompile(`(?m)^([ \t]*)(\w+)\.AddService\(.*$`)
+	returnAppRe     = regexp.MustCompile(`(?m)^([ \t]*)return (\w+)\s*$`)
+)
+
+// genController create controller Name and register it in main.go
+func genController(args []string) error {
+	data, dir, err := parseGenArgs("controller", "Controller", args)
+	if err != nil {
+		return err
+	}
+
+	file := filepath.Join(dir, snakeCase(data.Type)+".go")
+	if err := writeTemplate(file, controllerTemplate, data); err != nil {
+		return err
+	}
+
+	fmt.Println("created", file)
+	return wire(dir, "AddController(new("+data.Type+"))", false)
+}
+
+// genService create service Name and register it in main.go
+func genService(args []string) error {
+	data, dir, err := parseGenArgs("service", "Service", args)
+	if err != nil {
+		return err
+	}
+
+	file := filepath.Join(dir, snakeCase(data.Type)+".go")
+	if err := writeTemplate(file, serviceTemplate, data); err != nil {
+		return err
+	}
+
+	fmt.Println("created", file)
+	return wire(dir, "AddService(new("+data.Type+"))", true)
+}
+
+// genClient generate typed client, see clientgen command
+func genClient(args []string) error {
+	return clientcmd.Run("gen client", args)
+}
+
+// parseGenArgs parse name and -dir of gen command, suffix is appended to type name
+func parseGenArgs(kind, suffix string, args []string) (genData, string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return genData{}, "", fmt.Errorf("gen %s require name", kind)
+	}
+
+	flags := flag.NewFlagSet("gen "+kind, flag.ExitOnError)
+	dir := flags.String("dir", ".", "directory of main package")
+	flags.Parse(args[1:])
+
+	name := strings.TrimSuffix(args[0], suffix)
+	if name == "" || !isIdent(name) {
+		return genData{}, "", fmt.Errorf("invalid %s name %q", kind, args[0])
+	}
+
+	name = strings.ToUpper(name[:1]) + name[1:]
+	pkg, err := packageName(*dir)
+	if err != nil {
+		return genData{}, "", err
+	}
+
+	return genData{
+		Package: pkg,
+		Name:    name,
+		Type:    name + suffix,
+		Path:    strings.Replace(snakeCase(name), "_", "-", -1) + "s",
+	}, *dir, nil
+}
+
+// wire add call into main.go of dir.
+// Services are added before controllers, so controllers can link them
+func wire(dir, call string, service bool) error {
+	file := filepath.Join(dir, "main.go")
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("add %s manually: %v", call, err)
+	}
+
+	src := string(raw)
+	if strings.Contains(src, call) {
+		return nil
+	}
+
+	var at, indent, app string
+	if service {
+		if loc := addServiceRe.FindAllStringSubmatchIndex(src, -1); loc != nil {
+			at, indent, app = after(src, loc[len(loc)-1])
+		} else if loc := addControllerRe.FindStringSubmatchIndex(src); loc != nil {
+			at, indent, app = before(src, loc)
+		}
+	} else if loc := addControllerRe.FindAllStringSubmatchIndex(src, -1); loc != nil {
+		at, indent, app = after(src, loc[len(loc)-1])
+	}
+
+	if app == "" {
+		if loc := runServerRe.FindStringSubmatchIndex(src); loc != nil {
+			at, indent, app = before(src, loc)
+		} else if loc := returnAppRe.FindStringSubmatchIndex(src); loc != nil {
+			at, indent, app = before(src, loc)
+		}
+	}
+
+	if app == "" {
+		return fmt.Errorf("can not find where to register in %s, add %s manually", file, call)
+	}
+
+	i := len(src) - len(at)
+	src = src[:i] + indent + app + "." + call + "\n" + at
+	if err := os.WriteFile(file, []byte(src), 0644); err != nil {
+		return err
+	}
+
+	fmt.Println("registered in", file)
+	return nil
+}
+
+// before return source starting at line of match loc, with its indentation and receiver
+func before(src string, loc []int) (string, string, string) {
+	return src[loc[0]:], src[loc[2]:loc[3]], src[loc[4]:loc[5]]
+}
+
+// after return source after line of match loc, with its indentation and receiver
+func after(src string, loc []int) (string, string, string) {
+	end := loc[1]
+	if end < len(src) && src[end] == '\n' {
+		end++
+	}
+
+	return src[end:], src[loc[2]:loc[3]], src[loc[4]:loc[5]]
+}
+
+// packageName return package name of Go files in dir, default is main
+func packageName(dir string) (string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return "", err
+	}
+
+	for _, file := range files {
+		f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.PackageClauseOnly)
+		if err == nil && !strings.HasSuffix(f.Name.Name, "_test") {
+			return f.Name.Name, nil
+		}
+	}
+
+	return "main", nil
+}
+
+// snakeCase return name in snake case, such as user_controller for UserController
+func snakeCase(name string) string {
+	var sb strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				sb.WriteByte('_')
+			}
+
+			r = unicode.ToLower(r)
+		}
+
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
+
+func isIdent(name string) bool {
+	for i, r := range name {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,64 @@
+// Command minirest scaffold minirest projects, controllers and services.
+//
+// Usage:
+//
+//	minirest new <module> [dir]             create project with main, config and health controller
+//	minirest gen controller <Name> [-dir .] create controller and add it into main
+//	minirest gen service <Name> [-dir .]    create service and add it into main
+//	minirest gen client [clientgen flags]   generate typed client, see clientgen command
+//	minirest routes [package]               print route table of app
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `usage:
+	minirest new <module> [dir]
+	minirest gen controller <Name> [-dir .]
+	minirest gen service <Name> [-dir .]
+	minirest gen client (-spec file|url | -app package) [-pkg client] [-o file]
+	minirest routes [package]
+`
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "minirest:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "new":
+		return newProject(args[1:])
+	case "gen":
+		if len(args) < 2 {
+			return fmt.Errorf("gen require controller, service or client")
+		}
+
+		switch args[1] {
+		case "controller":
+			return genController(args[2:])
+		case "service":
+			return genService(args[2:])
+		case "client":
+			return genClient(args[2:])
+		}
+
+		return fmt.Errorf("unknown gen target %q", args[1])
+	case "routes":
+		return routes(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+		return nil
+	}
+
+	return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+}
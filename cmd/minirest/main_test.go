@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tamboto2000/minirest"
+)
+
+// captureStdout return what fn write to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+	stdout := os.Stdout
+	os.Stdout = f
+	defer func() { os.Stdout = stdout }()
+	fn()
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(out)
+}
+
+// goCmd run go command in dir without network
+func goCmd(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+
+	return string(out)
+}
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+	raw, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(raw)
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{args: []string{"gen"}, err: "gen require controller, service or client"},
+		{args: []string{"gen", "model", "User"}, err: `unknown gen target "model"`},
+		{args: []string{"deploy"}, err: `unknown command "deploy"`},
+		{args: []string{"new"}, err: "new require module path"},
+		{args: []string{"gen", "controller"}, err: "gen controller require name"},
+		{args: []string{"gen", "controller", "-dir", "."}, err: "gen controller require name"},
+		{args: []string{"gen", "service", "1mail"}, err: `invalid service name "1mail"`},
+		{args: []string{"gen", "controller", "Controller"}, err: `invalid controller name "Controller"`},
+		{args: []string{"gen", "client"}, err: "exactly one of -spec and -app is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			err := run(tt.args)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+				t.Errorf("err = %v, want %q", err, tt.err)
+			}
+		})
+	}
+
+	if out := captureStdout(t, func() { run([]string{"help"}) }); out != usage {
+		t.Errorf("help = %q", out)
+	}
+}
+
+func TestNewProject(t *testing.T) {
+	if testing.Short() {
+		t.Skip("build generated project with go command")
+	}
+
+	// dependencies are resolved from module cache and the minirest in this repository
+	t.Setenv("GOPROXY", "off")
+	t.Setenv("GOFLAGS", "-mod=mod")
+	root, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "shop")
+	captureStdout(t, func() {
+		if err := run([]string{"new", "example.com/shop", dir}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	for name := range projectFiles {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("file %s is not created: %v", name, err)
+		}
+	}
+
+	if gomod := readFile(t, filepath.Join(dir, "go.mod")); !strings.HasPrefix(gomod, "module example.com/shop\n") {
+		t.Errorf("go.mod =\n%s", gomod)
+	}
+
+	if err := run([]string{"new", "example.com/shop", dir}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("new into existing dir err = %v", err)
+	}
+
+	out := captureStdout(t, func() {
+		for _, args := range [][]string{
+			{"gen", "controller", "OrderItem", "-dir", dir},
+			{"gen", "service", "mailService", "-dir", dir},
+			{"gen", "service", "Payment", "-dir", dir},
+		} {
+			if err := run(args); err != nil {
+				t.Fatalf("%s: %v", strings.Join(args, " "), err)
+			}
+		}
+	})
+
+	for _, name := range []string{"order_item_controller.go", "mail_service.go", "payment_service.go"} {
+		if !strings.Contains(out, "created "+filepath.Join(dir, name)) {
+			t.Errorf("output doesn't report %s:\n%s", name, out)
+		}
+	}
+
+	// services are registered before controllers, in order of creation
+	want := "\tmn.AddService(new(MailService))\n" +
+		"\tmn.AddService(new(PaymentService))\n" +
+		"\tmn.AddController(new(HealthController))\n" +
+		"\tmn.AddController(new(OrderItemController))\n"
+	if main := readFile(t, filepath.Join(dir, "main.go")); !strings.Contains(main, want) {
+		t.Errorf("main.go =\n%s\nwant registrations\n%s", main, want)
+	}
+
+	if err := run([]string{"gen", "controller", "OrderItem", "-dir", dir}); err == nil {
+		t.Error("gen existing controller succeeded")
+	}
+
+	if ctrl := readFile(t, filepath.Join(dir, "order_item_controller.go")); !strings.Contains(ctrl, `ep.BasePath("/order-items")`) {
+		t.Errorf("controller =\n%s", ctrl)
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, "go.mod"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.WriteString("\nrequire github.com/tamboto2000/minirest v0.0.0\n\nreplace github.com/tamboto2000/minirest => " + root + "\n")
+	f.Close()
+	goCmd(t, dir, "mod", "tidy")
+	goCmd(t, dir, "vet", "./...")
+	goCmd(t, dir, "test", "./...")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.Chdir(wd)
+	routes := captureStdout(t, func() {
+		if err := run([]string{"routes"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	for _, want := range []string{"/health", "/order-items/:id", "OrderItemController.Create"} {
+		if !strings.Contains(routes, want) {
+			t.Errorf("routes doesn't contain %q:\n%s", want, routes)
+		}
+	}
+}
+
+type itemController struct{}
+
+func (ctrl *itemController) Get(id int) *minirest.ResponseBuilder {
+	return new(minirest.ResponseBuilder).Ok(id)
+}
+
+func (ctrl *itemController) Endpoints() *minirest.Endpoints {
+	ep := new(minirest.Endpoints)
+	ep.GET("/items/:id", ctrl.Get)
+	ep.Doc("GET", "/items/:id", minirest.EndpointDoc{Response: 0})
+
+	return ep
+}
+
+func TestGenClient(t *testing.T) {
+	mn := minirest.New()
+	mn.AddController(new(itemController))
+	raw, err := json.Marshal(mn.OpenAPIDocument(minirest.OpenAPIOption{Title: "Items"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "openapi.json")
+	if err := os.WriteFile(spec, raw, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "client.go")
+	if err := run([]string{"gen", "client", "-spec", spec, "-pkg", "items", "-o", out}); err != nil {
+		t.Fatal(err)
+	}
+
+	src := readFile(t, out)
+	for _, want := range []string{"package items", "func (c *Client) ItemControllerGet(ctx context.Context, id int64) (int64, error)"} {
+		if !strings.Contains(src, want) {
+			t.Errorf("client doesn't contain %q", want)
+		}
+	}
+}
+
+func TestWire(t *testing.T) {
+	tests := []struct {
+		name    string
+		main    string
+		call    string
+		service bool
+		want    string
+	}{
+		{
+			name: "controller after last controller",
+			main: "func main() {\n\tapp := minirest.New()\n\tapp.AddController(new(A))\n\tapp.AddController(new(B))\n\tapp.RunServer()\n}\n",
+			call: "AddController(new(C))",
+			want: "\tapp.AddController(new(B))\n\tapp.AddController(new(C))\n\tapp.RunServer()\n",
+		},
+		{
+			name: "controller before RunServer",
+			main: "func main() {\n\tmn := minirest.New()\n\tmn.RunServer()\n}\n",
+			call: "AddController(new(C))",
+			want: "\tmn.AddController(new(C))\n\tmn.RunServer()\n",
+		},
+		{
+			name: "controller before return of app",
+			main: "func NewApp() *minirest.Minirest {\n\tmn := minirest.New()\n\n\treturn mn\n}\n",
+			call: "AddController(new(C))",
+			want: "\tmn.AddController(new(C))\n\treturn mn\n",
+		},
+		{
+			name:    "service before first controller",
+			main:    "func main() {\n\tmn := minirest.New()\n\tmn.AddController(new(A))\n}\n",
+			call:    "AddService(new(S))",
+			service: true,
+			want:    "\tmn.AddService(new(S))\n\tmn.AddController(new(A))\n",
+		},
+		{
+			name: "already registered",
+			main: "func main() {\n\tmn.AddController(new(C))\n}\n",
+			call: "AddController(new(C))",
+			want: "func main() {\n\tmn.AddController(new(C))\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			file := filepath.Join(dir, "main.go")
+			if err := os.WriteFile(file, []byte("package main\n\n"+tt.main), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			captureStdout(t, func() {
+				if err := wire(dir, tt.call, tt.service); err != nil {
+					t.Fatal(err)
+				}
+			})
+
+			if got := readFile(t, file); !strings.Contains(got, tt.want) {
+				t.Errorf("main.go =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+	if err := wire(dir, "AddController(new(C))", false); err == nil || !strings.Contains(err.Error(), "add AddController(new(C)) manually") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestNames(t *testing.T) {
+	for name, want := range map[string]string{
+		"UserController":  "user_controller",
+		"HTTPServer":      "http_server",
+		"OrderItem":       "order_item",
+		"userID":          "user_id",
+		"already_snake":   "already_snake",
+		"APIKeyService":   "api_key_service",
+		"Service2Handler": "service2_handler",
+	} {
+		if got := snakeCase(name); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", name, got, want)
+		}
+	}
+
+	for name, want := range map[string]bool{"User": true, "_user": true, "user2": true, "2user": false, "user-x": false} {
+		if got := isIdent(name); got != want {
+			t.Errorf("isIdent(%q) = %v", name, got)
+		}
+	}
+}
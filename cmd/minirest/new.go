@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"text/template"
+)
+
+// project files, keyed by file name
+var projectFiles = map[string]string{
+	"go.mod": `module {{.Module}}
+
+go 1.21
+`,
+	"main.go": `package main
+
+import (
+	"github.com/tamboto2000/minirest"
+)
+
+func main() {
+	cfg := LoadConfig()
+	mn := NewApp()
+	mn.ServePort(cfg.Port)
+	mn.RunServer()
+}
+
+// NewApp create app with all controllers and services registered
+func NewApp() *minirest.Minirest {
+	mn := minirest.New()
+	mn.AddController(new(HealthController))
+
+	return mn
+}
+`,
+	"config.go": `package main
+
+import (
+	"os"
+)
+
+// Config is configuration of the app, read from environment variables
+type Config struct {
+	Port string
+}
+
+// LoadConfig read Config from environment variables
+func LoadConfig() Config {
+	cfg := Config{Port: "8080"}
+	if port := os.Getenv("PORT"); port != "" {
+		cfg.Port = port
+	}
+
+	return cfg
+}
+`,
+	"health_controller.go": `package main
+
+import (
+	"github.com/tamboto2000/minirest"
+)
+
+// HealthController report health of the app
+type HealthController struct{}
+
+// Check return status of the app
+func (ctrl *HealthController) Check() *minirest.ResponseBuilder {
+	return new(minirest.ResponseBuilder).Ok(map[string]string{"status": "ok"})
+}
+
+// Endpoints register endpoints of HealthController
+func (ctrl *HealthController) Endpoints() *minirest.Endpoints {
+	ep := new(minirest.Endpoints)
+	ep.GET("/health", ctrl.Check)
+
+	return ep
+}
+`,
+	"health_controller_test.go": `package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthCheck(t *testing.T) {
+	w := httptest.NewRecorder()
+	NewApp().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+`,
+}
+
+// newProject create project of module in dir, default is last element of module
+func newProject(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("new require module path")
+	}
+
+	module := args[0]
+	dir := path.Base(module)
+	if len(args) > 1 {
+		dir = args[1]
+	}
+
+	if _, err := os.Stat(dir); err == nil {
+		return fmt.Errorf("%s already exists", dir)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data := struct{ Module string }{module}
+	for name, text := range projectFiles {
+		if err := writeTemplate(filepath.Join(dir, name), text, data); err != nil {
+			return err
+		}
+	}
+
+	tidy := exec.Command("go", "mod", "tidy")
+	tidy.Dir = dir
+	tidy.Stdout = os.Stdout
+	tidy.Stderr = os.Stderr
+	if err := tidy.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "minirest: go mod tidy failed, run it in %s to add dependencies\n", dir)
+	}
+
+	fmt.Printf("created %s in %s\n", module, dir)
+	return nil
+}
+
+// writeTemplate execute template text with data into new file name
+func writeTemplate(name, text string, data interface{}) error {
+	tmpl, err := template.New(filepath.Base(name)).Parse(text)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	if err := tmpl.Execute(f, data); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/tamboto2000/minirest"
+)
+
+// routes print route table of app in package, default is current directory.
+// The app is run with minirest.RoutesEnv set, so RunServer print the routes instead of serving
+func routes(args []string) error {
+	pkg := "."
+	if len(args) > 0 {
+		pkg = args[0]
+	}
+
+	cmd := exec.Command("go", "run", pkg)
+	cmd.Env = append(os.Environ(), minirest.RoutesEnv+"=1")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
@@ -0,0 +1,142 @@
+package minirest
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// ETagMode set how ETag is generated from response body
+type ETagMode int
+
+// ETag generation modes
+const (
+	// ETagInherit use mode set by Minirest.AutoETag
+	ETagInherit ETagMode = iota
+	// ETagOff disable ETag generation
+	ETagOff
+	// ETagStrong generate strong validator
+	ETagStrong
+	// ETagWeak generate weak validator
+	ETagWeak
+)
+
+// ETagFunc return current entity tag of resource requested by r.
+// Return empty string if resource doesn't exist
+type ETagFunc func(r *http.Request, params httprouter.Params) (string, error)
+
+func generateETag(data []byte, mode ETagMode, encoded bool) string {
+	if mode != ETagStrong && mode != ETagWeak {
+		return ""
+	}
+
+	sum := sha1.Sum(data)
+	tag := base64.RawURLEncoding.EncodeToString(sum[:])
+	if mode == ETagWeak {
+		return `W/"` + tag + `"`
+	}
+
+	// strong validator must differ between content codings
+	if encoded {
+		tag += "-gzip"
+	}
+
+	return `"` + tag + `"`
+}
+
+// parseETags split If-Match and If-None-Match header value into entity tags
+func parseETags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+// etagMatch compare entity tags a and b, see RFC 9110 section 8.8.3.2
+func etagMatch(a, b string, weak bool) bool {
+	aWeak, bWeak := strings.HasPrefix(a, "W/"), strings.HasPrefix(b, "W/")
+	if !weak && (aWeak || bWeak) {
+		return false
+	}
+
+	return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+}
+
+func etagListMatch(header, etag string, weak bool) bool {
+	for _, tag := range parseETags(header) {
+		if tag == "*" && etag != "" {
+			return true
+		}
+
+		if etag != "" && etagMatch(tag, etag, weak) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// notModified report whether response of GET or HEAD request
+// can be replaced with 304 Not Modified, see RFC 9110 section 13.2.2
+func notModified(r *http.Request, code int, etag string, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if code < 200 || code >= 300 {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagListMatch(inm, etag, true)
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified.IsZero() {
+		return false
+	}
+
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+
+	return !lastModified.Truncate(time.Second).After(t)
+}
+
+// ifMatchHandler reject PUT, PATCH and DELETE request with 412 Precondition Failed
+// when If-Match header doesn't match current entity tag of the resource,
+// so concurrent updates don't overwrite each other
+func (mn *Minirest) ifMatchHandler(current ETagFunc, next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		ifMatch := r.Header.Get("If-Match")
+		if ifMatch == "" {
+			next(w, r, p)
+			return
+		}
+
+		etag, err := current(r, p)
+		if err != nil {
+			// error of ETagFunc can expose internals, so it's only logged
+			mn.logRequest(r, slog.LevelError, "minirest: entity tag failed", "error", err)
+			mn.writeResponse(w, r, new(ResponseBuilder).InternalError("internal server error"))
+			return
+		}
+
+		if !etagListMatch(ifMatch, etag, false) {
+			mn.writeResponse(w, r, new(ResponseBuilder).PreconditionFailed("resource has been modified"))
+			return
+		}
+
+		next(w, r, p)
+	}
+}
@@ -0,0 +1,164 @@
+package minirest
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestAutoETag(t *testing.T) {
+	mn := newTestApp("GET", "/items/:id", func(id int) *ResponseBuilder {
+		if id == 0 {
+			return new(ResponseBuilder).Ok(id).AutoETag(ETagOff)
+		}
+
+		return echo(id)
+	})
+
+	mn.AutoETag(ETagWeak)
+	w := serve(mn, "GET", "/items/1", nil)
+	etag := w.Header().Get("ETag")
+	if w.Code != 200 || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("status = %d, ETag %q", w.Code, etag)
+	}
+
+	if other := serve(mn, "GET", "/items/2", nil).Header().Get("ETag"); other == etag {
+		t.Errorf("different bodies have the same ETag %q", etag)
+	}
+
+	if got := serve(mn, "GET", "/items/0", nil).Header().Get("ETag"); got != "" {
+		t.Errorf("ETag = %q, want none when disabled per response", got)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		header string
+		status int
+	}{
+		{name: "match", method: "GET", header: etag, status: 304},
+		{name: "strong tag match weakly", method: "GET", header: strings.TrimPrefix(etag, "W/"), status: 304},
+		{name: "mismatch", method: "GET", header: `"other"`, status: 200},
+		{name: "any", method: "GET", header: "*", status: 304},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/items/1", nil)
+			r.Header.Set("If-None-Match", tt.header)
+			w := httptest.NewRecorder()
+			mn.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+
+			if tt.status == 304 && (w.Body.Len() != 0 || w.Header().Get("ETag") != etag) {
+				t.Errorf("304 has body %q, ETag %q", w.Body, w.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestAutoETagGzip(t *testing.T) {
+	callback := func(id int) *ResponseBuilder { return echo(id) }
+	mn := newTestApp("GET", "/items/:id", callback)
+	mn.AutoETag(ETagStrong)
+	plain := serve(mn, "GET", "/items/1", nil).Header().Get("ETag")
+
+	mn = New()
+	mn.Gzip = true
+	mn.AutoETag(ETagStrong)
+	mn.AddController(&testController{method: "GET", path: "/items/:id", callback: callback})
+	r := httptest.NewRequest("GET", "/items/1", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	mn.ServeHTTP(w, r)
+
+	// strong validator must differ between content codings
+	if got := w.Header().Get("ETag"); got == plain || !strings.HasSuffix(got, `-gzip"`) {
+		t.Errorf("gzip ETag = %q, plain %q", got, plain)
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	version := map[string]string{"1": `"v2"`, "2": `W/"v1"`}
+	called := 0
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.IfMatch(func(r *http.Request, params httprouter.Params) (string, error) {
+			if params.ByName("id") == "err" {
+				return "", errors.New("storage is down")
+			}
+
+			return version[params.ByName("id")], nil
+		})
+
+		ep.PUT("/items/:id", func(body testBody) *ResponseBuilder {
+			called++
+			return echo(body)
+		})
+		ep.GET("/items/:id", func(id string) *ResponseBuilder { return echo(id) })
+	})
+	logs := new(bytes.Buffer)
+	mn.Logger = SlogLogger(slog.NewJSONHandler(logs, nil))
+
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		ifMatch string
+		status  int
+	}{
+		{name: "no precondition", method: "PUT", target: "/items/1", status: 200},
+		{name: "match", method: "PUT", target: "/items/1", ifMatch: `"v2"`, status: 200},
+		{name: "match in list", method: "PUT", target: "/items/1", ifMatch: `"v1", "v2"`, status: 200},
+		{name: "stale", method: "PUT", target: "/items/1", ifMatch: `"v1"`, status: 412},
+		{name: "weak tag never match", method: "PUT", target: "/items/2", ifMatch: `W/"v1"`, status: 412},
+		{name: "any existing", method: "PUT", target: "/items/1", ifMatch: "*", status: 200},
+		{name: "any missing", method: "PUT", target: "/items/3", ifMatch: "*", status: 412},
+		{name: "etag error", method: "PUT", target: "/items/err", ifMatch: "*", status: 500},
+		{name: "get is not checked", method: "GET", target: "/items/1", ifMatch: `"v1"`, status: 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := called
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(`{}`))
+			if tt.ifMatch != "" {
+				r.Header.Set("If-Match", tt.ifMatch)
+			}
+
+			w := httptest.NewRecorder()
+			mn.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+
+			// error of ETagFunc is logged, not sent to client
+			if tt.status == 500 {
+				if strings.Contains(w.Body.String(), "storage is down") || !strings.Contains(w.Body.String(), "internal server error") {
+					t.Errorf("body = %s", w.Body)
+				}
+
+				if !strings.Contains(logs.String(), `"error":"storage is down"`) {
+					t.Errorf("logs = %s", logs)
+				}
+			}
+
+			if tt.status == 412 {
+				if called != before {
+					t.Error("callback is called for failed precondition")
+				}
+
+				if !strings.Contains(w.Body.String(), `"status":"`+MsgPreconditionFailed+`"`) {
+					t.Errorf("body = %s", w.Body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,476 @@
+package minirest
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type testQuery struct {
+	Name  string `schema:"name"`
+	Limit int    `schema:"limit"`
+}
+
+type testBody struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+// testController register callbacks given by test cases
+type testController struct {
+	method   string
+	path     string
+	callback interface{}
+}
+
+func (ctrl *testController) Endpoints() *Endpoints {
+	ep := new(Endpoints)
+	ep.Add(ctrl.method, ctrl.path, ctrl.callback)
+
+	return ep
+}
+
+// endpointsController register endpoints given by test cases
+type endpointsController struct {
+	register func(ep *Endpoints)
+}
+
+func (ctrl *endpointsController) Endpoints() *Endpoints {
+	ep := new(Endpoints)
+	ctrl.register(ep)
+
+	return ep
+}
+
+func newEndpointsApp(register func(ep *Endpoints)) *Minirest {
+	mn := New()
+	mn.AddController(&endpointsController{register: register})
+
+	return mn
+}
+
+// echo return callback argument as response body
+func echo(v interface{}) *ResponseBuilder {
+	return new(ResponseBuilder).Ok(v)
+}
+
+func newTestApp(method, path string, callback interface{}) *Minirest {
+	mn := New()
+	mn.AddController(&testController{method: method, path: path, callback: callback})
+
+	return mn
+}
+
+func serve(mn *Minirest, method, target string, body io.Reader) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	mn.ServeHTTP(w, httptest.NewRequest(method, target, body))
+
+	return w
+}
+
+// decodeBody decode body of Response envelope
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder) interface{} {
+	t.Helper()
+	var resp Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response %q: %v", w.Body.String(), err)
+	}
+
+	return resp.Body
+}
+
+func TestHandleWithoutBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		target   string
+		callback interface{}
+		status   int
+		want     interface{}
+	}{
+		{
+			name:     "int path variable",
+			path:     "/items/:id",
+			target:   "/items/42",
+			callback: func(id int) *ResponseBuilder { return echo(id) },
+			status:   200,
+			want:     float64(42),
+		},
+		{
+			name:     "negative int path variable",
+			path:     "/items/:id",
+			target:   "/items/-7",
+			callback: func(id int) *ResponseBuilder { return echo(id) },
+			status:   200,
+			want:     float64(-7),
+		},
+		{
+			name:     "invalid int path variable",
+			path:     "/items/:id",
+			target:   "/items/abc",
+			callback: func(id int) *ResponseBuilder { return echo(id) },
+			status:   400,
+		},
+		{
+			name:     "float64 path variable",
+			path:     "/items/:price",
+			target:   "/items/1.5",
+			callback: func(price float64) *ResponseBuilder { return echo(price) },
+			status:   200,
+			want:     1.5,
+		},
+		{
+			name:     "invalid float64 path variable",
+			path:     "/items/:price",
+			target:   "/items/x1",
+			callback: func(price float64) *ResponseBuilder { return echo(price) },
+			status:   400,
+		},
+		{
+			// ParseFloat accept Inf, and the response can't be encoded to JSON
+			name:     "non-finite float64 path variable",
+			path:     "/items/:price",
+			target:   "/items/-Inf",
+			callback: func(price float64) *ResponseBuilder { return echo(price) },
+			status:   500,
+		},
+		{
+			name:     "string path variable",
+			path:     "/items/:name",
+			target:   "/items/hello%20world",
+			callback: func(name string) *ResponseBuilder { return echo(name) },
+			status:   200,
+			want:     "hello world",
+		},
+		{
+			name:     "multiple path variables matched by index",
+			path:     "/users/:user/items/:id",
+			target:   "/users/john/items/3",
+			callback: func(user string, id int) *ResponseBuilder { return echo([]interface{}{user, id}) },
+			status:   200,
+			want:     []interface{}{"john", float64(3)},
+		},
+		{
+			name:     "pointer path variable",
+			path:     "/items/:id",
+			target:   "/items/9",
+			callback: func(id *int) *ResponseBuilder { return echo(*id) },
+			status:   200,
+			want:     float64(9),
+		},
+		{
+			name:     "invalid pointer path variable",
+			path:     "/items/:id",
+			target:   "/items/nine",
+			callback: func(id *int) *ResponseBuilder { return echo(id) },
+			status:   400,
+		},
+		{
+			// only int, float64 and string are converted, other types are left zero
+			name:     "unsupported path variable type",
+			path:     "/items/:id",
+			target:   "/items/5",
+			callback: func(id int64) *ResponseBuilder { return echo(id) },
+			status:   200,
+			want:     float64(0),
+		},
+		{
+			name:     "struct query",
+			path:     "/items",
+			target:   "/items?name=pen&limit=10",
+			callback: func(q testQuery) *ResponseBuilder { return echo(q) },
+			status:   200,
+			want:     map[string]interface{}{"Name": "pen", "Limit": float64(10)},
+		},
+		{
+			name:     "pointer struct query",
+			path:     "/items",
+			target:   "/items?name=pen",
+			callback: func(q *testQuery) *ResponseBuilder { return echo(q) },
+			status:   200,
+			want:     map[string]interface{}{"Name": "pen", "Limit": float64(0)},
+		},
+		{
+			name:     "pointer struct query without queries",
+			path:     "/items",
+			target:   "/items",
+			callback: func(q *testQuery) *ResponseBuilder { return echo(q != nil) },
+			status:   200,
+			want:     true,
+		},
+		{
+			name:     "path variable with struct query",
+			path:     "/items/:id",
+			target:   "/items/4?limit=2",
+			callback: func(id int, q testQuery) *ResponseBuilder { return echo([]interface{}{id, q.Limit}) },
+			status:   200,
+			want:     []interface{}{float64(4), float64(2)},
+		},
+		{
+			name:     "invalid query value",
+			path:     "/items",
+			target:   "/items?limit=many",
+			callback: func(q testQuery) *ResponseBuilder { return echo(q) },
+			status:   400,
+		},
+		{
+			name:     "unknown query key",
+			path:     "/items",
+			target:   "/items?color=red",
+			callback: func(q testQuery) *ResponseBuilder { return echo(q) },
+			status:   400,
+		},
+	}
+
+	silenceLog(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mn := newTestApp("GET", tt.path, tt.callback)
+			w := serve(mn, "GET", tt.target, nil)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body)
+			}
+
+			if tt.status != 200 {
+				return
+			}
+
+			if got := decodeBody(t, w); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("body = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleWithoutBodyErrorMessage(t *testing.T) {
+	mn := newTestApp("DELETE", "/items/:id", func(id int) *ResponseBuilder { return echo(id) })
+	w := serve(mn, "DELETE", "/items/abc", nil)
+	want := `{"statusCode":400,"status":"bad_request","description":"id is not type int"}` + "\n"
+	if w.Body.String() != want {
+		t.Errorf("body = %q, want %q", w.Body.String(), want)
+	}
+}
+
+func TestHandleWithBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		callback interface{}
+		status   int
+		want     interface{}
+	}{
+		{
+			name:     "struct",
+			body:     `{"name":"john","age":30}`,
+			callback: func(b testBody) *ResponseBuilder { return echo(b) },
+			status:   200,
+			want:     map[string]interface{}{"name": "john", "age": float64(30)},
+		},
+		{
+			name:     "pointer struct",
+			body:     `{"name":"john"}`,
+			callback: func(b *testBody) *ResponseBuilder { return echo(b) },
+			status:   200,
+			want:     map[string]interface{}{"name": "john", "age": float64(0)},
+		},
+		{
+			name:     "map",
+			body:     `{"a":1}`,
+			callback: func(b map[string]int) *ResponseBuilder { return echo(b) },
+			status:   200,
+			want:     map[string]interface{}{"a": float64(1)},
+		},
+		{
+			name:     "slice",
+			body:     `[1,2,3]`,
+			callback: func(b []int) *ResponseBuilder { return echo(len(b)) },
+			status:   200,
+			want:     float64(3),
+		},
+		{
+			name:     "unknown fields are ignored",
+			body:     `{"name":"john","color":"red"}`,
+			callback: func(b testBody) *ResponseBuilder { return echo(b.Name) },
+			status:   200,
+			want:     "john",
+		},
+		{
+			// only the first value is decoded
+			name:     "trailing data",
+			body:     `{"name":"a"} {"name":"b"}`,
+			callback: func(b testBody) *ResponseBuilder { return echo(b.Name) },
+			status:   200,
+			want:     "a",
+		},
+		{
+			name:     "invalid JSON",
+			body:     `{"name":`,
+			callback: func(b testBody) *ResponseBuilder { return echo(b) },
+			status:   400,
+		},
+		{
+			name:     "empty body",
+			body:     ``,
+			callback: func(b testBody) *ResponseBuilder { return echo(b) },
+			status:   400,
+		},
+		{
+			name:     "mismatched type",
+			body:     `{"age":"old"}`,
+			callback: func(b testBody) *ResponseBuilder { return echo(b) },
+			status:   400,
+		},
+	}
+
+	for _, method := range []string{"POST", "PUT", "PATCH"} {
+		for _, tt := range tests {
+			t.Run(method+" "+tt.name, func(t *testing.T) {
+				mn := newTestApp(method, "/items", tt.callback)
+				w := serve(mn, method, "/items", strings.NewReader(tt.body))
+				if w.Code != tt.status {
+					t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body)
+				}
+
+				if tt.status != 200 {
+					return
+				}
+
+				if got := decodeBody(t, w); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("body = %#v, want %#v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestPathAssigner(t *testing.T) {
+	tests := []struct {
+		name    string
+		typ     interface{}
+		value   string
+		want    interface{}
+		wantErr string
+	}{
+		{name: "string", typ: "", value: "abc", want: "abc"},
+		{name: "empty string", typ: "", value: "", want: ""},
+		{name: "int", typ: 0, value: "12", want: 12},
+		{name: "int with plus sign", typ: 0, value: "+12", want: 12},
+		{name: "int overflow", typ: 0, value: "99999999999999999999", wantErr: "id is not type int"},
+		{name: "int from float", typ: 0, value: "1.5", wantErr: "id is not type int"},
+		{name: "float64", typ: 0.0, value: "2.25", want: 2.25},
+		{name: "float64 exponent", typ: 0.0, value: "1e3", want: 1000.0},
+		{name: "float64 invalid", typ: 0.0, value: "x", wantErr: "id is not type float64"},
+		{name: "unsupported bool", typ: false, value: "true", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			param := reflect.New(reflect.TypeOf(tt.typ)).Elem()
+			var err error
+			// unsupported kinds have no assigner and are left zero
+			if assign := pathAssignerFor(param.Kind()); assign != nil {
+				err = assign(param, "id", tt.value)
+			}
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := param.Interface(); got != tt.want {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+// float fields of typed requests are parsed with their own size
+func TestAssignFloat(t *testing.T) {
+	var f float32
+	param := reflect.ValueOf(&f).Elem()
+	if err := assignFloat(param, "price", "1.5"); err != nil || f != 1.5 {
+		t.Errorf("got %v, err %v", f, err)
+	}
+
+	if err := assignFloat(param, "price", "1e39"); err == nil || err.Error() != "price is not type float32" {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestBodyDecoder(t *testing.T) {
+	t.Run("value", func(t *testing.T) {
+		dest := reflect.New(reflect.TypeOf(testBody{}))
+		if err := bodyDecoder(ioutil.NopCloser(strings.NewReader(`{"name":"a"}`)), dest); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := dest.Elem().Interface().(testBody); got.Name != "a" {
+			t.Errorf("got %+v", got)
+		}
+	})
+
+	t.Run("pointer is allocated", func(t *testing.T) {
+		dest := reflect.New(reflect.TypeOf(&testBody{}))
+		if err := bodyDecoder(ioutil.NopCloser(strings.NewReader(`{"age":3}`)), dest); err != nil {
+			t.Fatal(err)
+		}
+
+		got := dest.Elem().Interface().(*testBody)
+		if got == nil || got.Age != 3 {
+			t.Errorf("got %+v", got)
+		}
+	})
+
+	t.Run("null into pointer", func(t *testing.T) {
+		dest := reflect.New(reflect.TypeOf(&testBody{}))
+		if err := bodyDecoder(ioutil.NopCloser(strings.NewReader(`null`)), dest); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := dest.Elem().Interface().(*testBody); got == nil {
+			t.Errorf("pointer is nil, want allocated")
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		dest := reflect.New(reflect.TypeOf(testBody{}))
+		if err := bodyDecoder(ioutil.NopCloser(strings.NewReader(`[`)), dest); err == nil {
+			t.Error("want error")
+		}
+	})
+}
+
+// silenceLog discard output of the standard logger until the test ends
+func silenceLog(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+}
+
+func TestMethodHead(t *testing.T) {
+	mn := New()
+	mn.AddController(&testController{method: "GET", path: "/items/:id", callback: func(id int) *ResponseBuilder { return echo(id) }})
+	mn.AddController(&testController{method: "HEAD", path: "/items/:id", callback: func(id int) *ResponseBuilder { return echo(id) }})
+	get := serve(mn, http.MethodGet, "/items/1", nil)
+	head := serve(mn, http.MethodHead, "/items/1", nil)
+	if head.Code != 200 || head.Body.Len() != 0 {
+		t.Fatalf("status = %d, body %q", head.Code, head.Body)
+	}
+
+	if want := strconv.Itoa(get.Body.Len()); head.Header().Get("Content-Length") != want {
+		t.Errorf("Content-Length = %q, want %q", head.Header().Get("Content-Length"), want)
+	}
+}
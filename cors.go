@@ -0,0 +1,226 @@
+package minirest
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// CORSOption set options for CORS headers
+type CORSOption struct {
+	// AllowOrigins is allowed origins. "*" allow any origin,
+	// and wildcard subdomain such as "https://*.example.com" allow any subdomain
+	AllowOrigins []string
+	// AllowOriginFunc report whether origin is allowed,
+	// it is called when origin doesn't match AllowOrigins
+	AllowOriginFunc func(origin string) bool
+	// AllowCredentials allow credentials. Allowed origin is reflected
+	// instead of "*" when credentials are allowed, as browsers reject the wildcard
+	AllowCredentials bool
+	// ExposeHeaders is response headers that can be read by browser scripts
+	ExposeHeaders []string
+	// AllowHeaders is allowed request headers.
+	// If empty, headers requested in preflight request are allowed
+	AllowHeaders []string
+	// AllowMethods is allowed methods.
+	// If empty, methods registered for the requested path are allowed
+	AllowMethods []string
+	// MaxAge is how long preflight response can be cached
+	MaxAge time.Duration
+}
+
+// CORS set CORS for all endpoints.
+// Preflight requests are answered with 204 No Content,
+// and CORS headers are also added to the actual responses
+func (mn *Minirest) CORS(opt CORSOption) {
+	mn.cors = &opt
+	mn.router.HandleMethodNotAllowed = true
+	mn.router.HandleOPTIONS = true
+	mn.router.GlobalOPTIONS = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mn.preflight(w, r, mn.cors)
+	})
+}
+
+// allowedMethods return methods registered for path, ordered by methodOrder
+func (mn *Minirest) allowedMethods(path string) []string {
+	var methods []string
+	for _, method := range methodOrder {
+		if !mn.methods[method] {
+			continue
+		}
+
+		if handle, _, _ := mn.router.Lookup(method, path); handle != nil {
+			methods = append(methods, method)
+		}
+	}
+
+	return methods
+}
+
+// order of methods in Allow and Access-Control-Allow-Methods headers
+var methodOrder = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodConnect,
+	http.MethodTrace,
+}
+
+// equal report whether opt and other are the same options
+func (opt *CORSOption) equal(other *CORSOption) bool {
+	if opt == other {
+		return true
+	}
+
+	a, b := *opt, *other
+	if reflect.ValueOf(a.AllowOriginFunc).Pointer() != reflect.ValueOf(b.AllowOriginFunc).Pointer() {
+		return false
+	}
+
+	a.AllowOriginFunc, b.AllowOriginFunc = nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
+// originAllowed report whether origin is allowed by opt
+func (opt *CORSOption) originAllowed(origin string) bool {
+	for _, allowed := range opt.AllowOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+
+		// wildcard subdomain, such as https://*.example.com
+		if i := strings.Index(allowed, "*."); i != -1 {
+			prefix, suffix := strings.ToLower(allowed[:i]), strings.ToLower(allowed[i+1:])
+			lower := strings.ToLower(origin)
+			if strings.HasPrefix(lower, prefix) && strings.HasSuffix(lower, suffix) &&
+				len(lower) > len(prefix)+len(suffix) {
+				return true
+			}
+		}
+	}
+
+	if opt.AllowOriginFunc != nil {
+		return opt.AllowOriginFunc(origin)
+	}
+
+	return false
+}
+
+// setOrigin set Access-Control-Allow-Origin, Access-Control-Allow-Credentials and Vary headers.
+// It returns false if origin is not allowed
+func (opt *CORSOption) setOrigin(header http.Header, origin string) bool {
+	wildcard := len(opt.AllowOrigins) == 1 && opt.AllowOrigins[0] == "*" && !opt.AllowCredentials
+	// response depends on Origin unless any origin get the same "*"
+	if !wildcard {
+		header.Add("Vary", "Origin")
+	}
+
+	if !opt.originAllowed(origin) {
+		return false
+	}
+
+	if wildcard {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+
+	if opt.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	return true
+}
+
+// preflight answer CORS preflight request
+func (mn *Minirest) preflight(w http.ResponseWriter, r *http.Request, opt *CORSOption) {
+	origin := r.Header.Get("Origin")
+	if opt != nil && origin != "" && r.Header.Get("Access-Control-Request-Method") != "" {
+		header := w.Header()
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		if opt.setOrigin(header, origin) {
+			if len(opt.ExposeHeaders) != 0 {
+				header.Set("Access-Control-Expose-Headers", strings.Join(opt.ExposeHeaders, ", "))
+			}
+
+			if len(opt.AllowHeaders) != 0 {
+				header.Set("Access-Control-Allow-Headers", strings.Join(opt.AllowHeaders, ", "))
+			} else if reqHeaders := r.Header.Get("Access-Control-Request-Headers"); reqHeaders != "" {
+				header.Set("Access-Control-Allow-Headers", reqHeaders)
+			}
+
+			methods := opt.AllowMethods
+			if len(methods) == 0 {
+				methods = mn.allowedMethods(r.URL.Path)
+			}
+
+			if len(methods) != 0 {
+				header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+			}
+
+			if opt.MaxAge > 0 {
+				header.Set("Access-Control-Max-Age", strconv.Itoa(int(opt.MaxAge/time.Second)))
+			}
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// preflightHandler answer preflight request of endpoints with their own CORS options
+func (mn *Minirest) preflightHandler(opt *CORSOption) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		allow := append(mn.allowedMethods(r.URL.Path), http.MethodOptions)
+		w.Header().Set("Allow", strings.Join(allow, ", "))
+		mn.preflight(w, r, opt)
+	}
+}
+
+// corsHandler add CORS headers to actual (non-preflight) response, using override
+// options of Endpoints if not nil. Options of Minirest are read on each request,
+// so Minirest.CORS can be called after AddController
+func (mn *Minirest) corsHandler(override *CORSOption, next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		opt := override
+		if opt == nil {
+			opt = mn.cors
+		}
+
+		if origin := r.Header.Get("Origin"); opt != nil && origin != "" {
+			opt.setActual(w.Header(), origin)
+		}
+
+		next(w, r, p)
+	}
+}
+
+// setActual set CORS headers of actual response
+func (opt *CORSOption) setActual(header http.Header, origin string) {
+	if opt.setOrigin(header, origin) && len(opt.ExposeHeaders) != 0 {
+		header.Set("Access-Control-Expose-Headers", strings.Join(opt.ExposeHeaders, ", "))
+	}
+}
+
+// fallbackCORS add CORS headers set by Minirest.CORS to responses of fallback handlers,
+// such as not found and panic responses, unless they are already set by the endpoint
+func (mn *Minirest) fallbackCORS(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if mn.cors == nil || origin == "" {
+		return
+	}
+
+	header := w.Header()
+	if header.Get("Access-Control-Allow-Origin") != "" || containsValue(header.Values("Vary"), "Origin") {
+		return
+	}
+
+	mn.cors.setActual(header, origin)
+}
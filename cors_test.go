@@ -0,0 +1,306 @@
+package minirest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// corsRequest serve request with Origin header and extra headers
+func corsRequest(mn *Minirest, method, target, origin string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	if origin != "" {
+		r.Header.Set("Origin", origin)
+	}
+
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+
+	w := httptest.NewRecorder()
+	mn.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestCORSOrigins(t *testing.T) {
+	tests := []struct {
+		name   string
+		opt    CORSOption
+		origin string
+		allow  string
+		vary   bool
+	}{
+		{name: "any", opt: CORSOption{AllowOrigins: []string{"*"}}, origin: "https://a.example", allow: "*"},
+		{name: "exact", opt: CORSOption{AllowOrigins: []string{"https://a.example"}}, origin: "https://a.example", allow: "https://a.example", vary: true},
+		{name: "case insensitive", opt: CORSOption{AllowOrigins: []string{"https://A.example"}}, origin: "https://a.example", allow: "https://a.example", vary: true},
+		{name: "not allowed", opt: CORSOption{AllowOrigins: []string{"https://a.example"}}, origin: "https://b.example", vary: true},
+		{name: "subdomain", opt: CORSOption{AllowOrigins: []string{"https://*.example.com"}}, origin: "https://api.example.com", allow: "https://api.example.com", vary: true},
+		{name: "nested subdomain", opt: CORSOption{AllowOrigins: []string{"https://*.example.com"}}, origin: "https://a.b.example.com", allow: "https://a.b.example.com", vary: true},
+		{name: "subdomain apex", opt: CORSOption{AllowOrigins: []string{"https://*.example.com"}}, origin: "https://example.com", vary: true},
+		{name: "subdomain suffix", opt: CORSOption{AllowOrigins: []string{"https://*.example.com"}}, origin: "https://evil-example.com", vary: true},
+		{name: "subdomain scheme", opt: CORSOption{AllowOrigins: []string{"https://*.example.com"}}, origin: "http://api.example.com", vary: true},
+		{
+			name:   "origin func",
+			opt:    CORSOption{AllowOriginFunc: func(origin string) bool { return strings.HasSuffix(origin, ".test") }},
+			origin: "https://a.test",
+			allow:  "https://a.test",
+			vary:   true,
+		},
+		{
+			name:   "credentials reflect origin",
+			opt:    CORSOption{AllowOrigins: []string{"*"}, AllowCredentials: true},
+			origin: "https://a.example",
+			allow:  "https://a.example",
+			vary:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mn := newTestApp("GET", "/items", func() *ResponseBuilder { return echo(1) })
+			mn.CORS(tt.opt)
+
+			w := corsRequest(mn, "GET", "/items", tt.origin)
+			if w.Code != 200 {
+				t.Fatalf("status = %d", w.Code)
+			}
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.allow {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.allow)
+			}
+
+			wantCredentials := ""
+			if tt.opt.AllowCredentials && tt.allow != "" {
+				wantCredentials = "true"
+			}
+
+			if got := w.Header().Get("Access-Control-Allow-Credentials"); got != wantCredentials {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, wantCredentials)
+			}
+
+			if vary := containsValue(w.Header().Values("Vary"), "Origin"); vary != tt.vary {
+				t.Errorf("Vary = %q, want Origin %v", w.Header().Values("Vary"), tt.vary)
+			}
+		})
+	}
+}
+
+func TestCORSActualResponse(t *testing.T) {
+	mn := newTestApp("GET", "/items", func() *ResponseBuilder { return echo(1) })
+	mn.CORS(CORSOption{AllowOrigins: []string{"https://a.example"}, ExposeHeaders: []string{"X-Total", "ETag"}})
+
+	w := corsRequest(mn, "GET", "/items", "https://a.example")
+	if got := w.Header().Get("Access-Control-Expose-Headers"); got != "X-Total, ETag" {
+		t.Errorf("Access-Control-Expose-Headers = %q", got)
+	}
+
+	// request without Origin is not CORS request
+	w = corsRequest(mn, "GET", "/items", "")
+	if len(w.Header().Values("Vary")) != 0 || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("CORS headers are set without Origin: %v", w.Header())
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.GET("/items", func() *ResponseBuilder { return echo(1) })
+		ep.POST("/items", func(body testBody) *ResponseBuilder { return echo(body) })
+		ep.DELETE("/items/:id", func(id int) *ResponseBuilder { return echo(id) })
+	})
+
+	mn.CORS(CORSOption{AllowOrigins: []string{"https://a.example"}, AllowCredentials: true, MaxAge: 10 * time.Minute})
+
+	w := corsRequest(mn, "OPTIONS", "/items", "https://a.example",
+		"Access-Control-Request-Method", "POST", "Access-Control-Request-Headers", "Content-Type, X-Token")
+	if w.Code != 204 {
+		t.Fatalf("status = %d", w.Code)
+	}
+
+	want := map[string]string{
+		"Access-Control-Allow-Origin":      "https://a.example",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "GET, POST",
+		"Access-Control-Allow-Headers":     "Content-Type, X-Token",
+		"Access-Control-Max-Age":           "600",
+	}
+
+	for key, value := range want {
+		if got := w.Header().Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+
+	wantVary := []string{"Access-Control-Request-Method", "Access-Control-Request-Headers", "Origin"}
+	if got := w.Header().Values("Vary"); !reflect.DeepEqual(got, wantVary) {
+		t.Errorf("Vary = %q, want %q", got, wantVary)
+	}
+
+	// methods are discovered per path
+	w = corsRequest(mn, "OPTIONS", "/items/1", "https://a.example", "Access-Control-Request-Method", "DELETE")
+	if got := w.Header().Get("Access-Control-Allow-Methods"); got != "DELETE" {
+		t.Errorf("Access-Control-Allow-Methods = %q, want DELETE", got)
+	}
+
+	// preflight from disallowed origin get no CORS headers
+	w = corsRequest(mn, "OPTIONS", "/items", "https://b.example", "Access-Control-Request-Method", "POST")
+	if w.Code != 204 || w.Header().Get("Access-Control-Allow-Origin") != "" || w.Header().Get("Access-Control-Allow-Methods") != "" {
+		t.Errorf("status = %d, headers %v", w.Code, w.Header())
+	}
+}
+
+func TestCORSMountAndHandler(t *testing.T) {
+	mn := New()
+	mn.Mount("/static", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method))
+	}))
+	mn.Handler("GET", "/metrics", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	mn.CORS(CORSOption{AllowOrigins: []string{"https://a.example"}})
+
+	for _, target := range []string{"/static/app.js", "/metrics"} {
+		w := corsRequest(mn, "GET", target, "https://a.example")
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://a.example" {
+			t.Errorf("Access-Control-Allow-Origin of %s = %q", target, got)
+		}
+	}
+
+	w := corsRequest(mn, "OPTIONS", "/static/app.js", "https://a.example", "Access-Control-Request-Method", "PUT")
+	if w.Code != 204 || w.Header().Get("Access-Control-Allow-Origin") != "https://a.example" || !strings.Contains(w.Header().Get("Access-Control-Allow-Methods"), "PUT") {
+		t.Errorf("preflight of mounted path: status = %d, headers %v", w.Code, w.Header())
+	}
+
+	// OPTIONS request that is not preflight is passed to mounted handler
+	if w := corsRequest(mn, "OPTIONS", "/static/app.js", "https://a.example"); w.Body.String() != "OPTIONS" {
+		t.Errorf("body = %q", w.Body)
+	}
+}
+
+func TestCORSPreflightAllowList(t *testing.T) {
+	mn := newTestApp("GET", "/items", func() *ResponseBuilder { return echo(1) })
+	mn.CORS(CORSOption{AllowOrigins: []string{"*"}, AllowMethods: []string{"GET", "PUT"}, AllowHeaders: []string{"X-Token"}})
+
+	w := corsRequest(mn, "OPTIONS", "/items", "https://a.example",
+		"Access-Control-Request-Method", "PUT", "Access-Control-Request-Headers", "X-Other")
+	if got := w.Header().Get("Access-Control-Allow-Methods"); got != "GET, PUT" {
+		t.Errorf("Access-Control-Allow-Methods = %q", got)
+	}
+
+	if got := w.Header().Get("Access-Control-Allow-Headers"); got != "X-Token" {
+		t.Errorf("Access-Control-Allow-Headers = %q", got)
+	}
+}
+
+func TestCORSEndpointsOverride(t *testing.T) {
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.GET("/public", func() *ResponseBuilder { return echo(1) })
+
+		admin := ep.Group("/admin")
+		admin.CORS(CORSOption{AllowOrigins: []string{"https://admin.example"}})
+		admin.GET("/users", func() *ResponseBuilder { return echo(1) })
+	})
+
+	mn.CORS(CORSOption{AllowOrigins: []string{"*"}})
+
+	if got := corsRequest(mn, "GET", "/public", "https://a.example").Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("public Access-Control-Allow-Origin = %q", got)
+	}
+
+	if got := corsRequest(mn, "GET", "/admin/users", "https://a.example").Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("admin Access-Control-Allow-Origin = %q, want none", got)
+	}
+
+	w := corsRequest(mn, "OPTIONS", "/admin/users", "https://admin.example", "Access-Control-Request-Method", "GET")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://admin.example" {
+		t.Errorf("admin preflight Access-Control-Allow-Origin = %q", got)
+	}
+}
+
+// error responses must have CORS headers, or browser scripts can't read them
+func TestCORSFallbackResponses(t *testing.T) {
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.GET("/items", func() *ResponseBuilder { return echo(1) })
+		ep.GET("/panic", func() *ResponseBuilder { panic("boom") })
+	})
+
+	mn.Logger = NopLogger
+	mn.CORS(CORSOption{AllowOrigins: []string{"https://a.example"}, ExposeHeaders: []string{"X-Total"}})
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		status int
+	}{
+		{name: "not found", method: "GET", target: "/missing", status: 404},
+		{name: "method not allowed", method: "PUT", target: "/items", status: 405},
+		{name: "panic", method: "GET", target: "/panic", status: 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := corsRequest(mn, tt.method, tt.target, "https://a.example")
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://a.example" {
+				t.Errorf("Access-Control-Allow-Origin = %q", got)
+			}
+
+			if got := w.Header().Get("Access-Control-Expose-Headers"); got != "X-Total" {
+				t.Errorf("Access-Control-Expose-Headers = %q", got)
+			}
+
+			if got := w.Header().Values("Vary"); !reflect.DeepEqual(got, []string{"Origin"}) {
+				t.Errorf("Vary = %q, want Origin once", got)
+			}
+		})
+	}
+
+	mn.NotFoundHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	if got := corsRequest(mn, "GET", "/missing", "https://a.example").Header().Get("Access-Control-Allow-Origin"); got != "https://a.example" {
+		t.Errorf("custom not found Access-Control-Allow-Origin = %q", got)
+	}
+}
+
+func TestCORSEndpointsConflict(t *testing.T) {
+	allowA := CORSOption{AllowOrigins: []string{"https://a.example"}}
+
+	// endpoints of the same path with equal options share the preflight
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.CORS(allowA)
+		ep.GET("/items", func() *ResponseBuilder { return echo(1) })
+
+		other := ep.Group("")
+		other.CORS(CORSOption{AllowOrigins: []string{"https://a.example"}})
+		other.POST("/items", func(body testBody) *ResponseBuilder { return echo(body) })
+	})
+
+	w := corsRequest(mn, "OPTIONS", "/items", "https://a.example", "Access-Control-Request-Method", "POST")
+	if got := w.Header().Get("Access-Control-Allow-Methods"); got != "GET, POST" {
+		t.Errorf("Access-Control-Allow-Methods = %q", got)
+	}
+
+	defer func() {
+		rcv := recover()
+		if rcv == nil || !strings.Contains(fmt.Sprint(rcv), "CORS options conflict") {
+			t.Errorf("recovered %v, want CORS conflict panic", rcv)
+		}
+	}()
+
+	newEndpointsApp(func(ep *Endpoints) {
+		ep.CORS(allowA)
+		ep.GET("/items", func() *ResponseBuilder { return echo(1) })
+
+		other := ep.Group("")
+		other.CORS(CORSOption{AllowOrigins: []string{"https://b.example"}})
+		other.POST("/items", func(body testBody) *ResponseBuilder { return echo(body) })
+	})
+}
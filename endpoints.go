@@ -1,18 +1,46 @@
 package minirest
 
+import (
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// endpoint kinds
+const (
+	kindREST = iota
+	kindSSE
+	kindWebSocket
+	kindTyped
+)
+
 type endpoint struct {
-	method   string
-	path     string
-	callback interface{}
+	method      string
+	path        string
+	callback    interface{}
+	kind        int
+	middlewares []handleToHandle
+	doc         *EndpointDoc
 }
 
 // Endpoints register handlers its path and method
 type Endpoints struct {
 	// Set to true for returning gzip encoded response on all endpoints
-	Gzip       bool
-	basePath   string
-	endpoints  []endpoint
-	middleware *handleChain
+	Gzip         bool
+	basePath     string
+	endpoints    []endpoint
+	middleware   *handleChain
+	sseHeartbeat time.Duration
+	wsOption     *WebSocketOption
+	ifMatch      ETagFunc
+	cache        *ResponseCache
+	cacheOption  CacheOption
+	cors         *CORSOption
+	groups       []*Endpoints
+	version      string
+	docs         map[string]EndpointDoc
+	accessLog    *accessLogger
+	noAccessLog  bool
 }
 
 // BasePath set base path for endpoints
@@ -20,34 +48,121 @@ func (ep *Endpoints) BasePath(path string) {
 	ep.basePath = path
 }
 
-// Add add endpoint with custom method
-func (ep *Endpoints) Add(method, path string, callback interface{}) {
-	ep.endpoints = append(ep.endpoints, endpoint{method, path, callback})
+// Group create route group with base path path nested under ep.
+// Group inherit base path, middlewares, Gzip, CORS and other settings of ep,
+// and can add its own on top of them
+func (ep *Endpoints) Group(path string) *Endpoints {
+	group := &Endpoints{basePath: path}
+	ep.groups = append(ep.groups, group)
+
+	return group
+}
+
+// Add add endpoint with custom method.
+// mds is middlewares only applied to this endpoint, executed after middlewares of Endpoints
+func (ep *Endpoints) Add(method, path string, callback interface{}, mds ...handleToHandle) {
+	ep.add(method, path, callback, kindREST, mds)
 }
 
 // GET add endpoint with method GET
-func (ep *Endpoints) GET(path string, callback interface{}) {
-	ep.endpoints = append(ep.endpoints, endpoint{"GET", path, callback})
+func (ep *Endpoints) GET(path string, callback interface{}, mds ...handleToHandle) {
+	ep.add("GET", path, callback, kindREST, mds)
 }
 
 // DELETE add endpoint with method DELETE
-func (ep *Endpoints) DELETE(path string, callback interface{}) {
-	ep.endpoints = append(ep.endpoints, endpoint{"DELETE", path, callback})
+func (ep *Endpoints) DELETE(path string, callback interface{}, mds ...handleToHandle) {
+	ep.add("DELETE", path, callback, kindREST, mds)
 }
 
 // POST add method endpoint with method POST
-func (ep *Endpoints) POST(path string, callback interface{}) {
-	ep.endpoints = append(ep.endpoints, endpoint{"POST", path, callback})
+func (ep *Endpoints) POST(path string, callback interface{}, mds ...handleToHandle) {
+	ep.add("POST", path, callback, kindREST, mds)
 }
 
 // PUT add method endpoint with method PUT
-func (ep *Endpoints) PUT(path string, callback interface{}) {
-	ep.endpoints = append(ep.endpoints, endpoint{"PUT", path, callback})
+func (ep *Endpoints) PUT(path string, callback interface{}, mds ...handleToHandle) {
+	ep.add("PUT", path, callback, kindREST, mds)
 }
 
 // PATCH add method endpoint with method PATCH
-func (ep *Endpoints) PATCH(path string, callback interface{}) {
-	ep.endpoints = append(ep.endpoints, endpoint{"PATCH", path, callback})
+func (ep *Endpoints) PATCH(path string, callback interface{}, mds ...handleToHandle) {
+	ep.add("PATCH", path, callback, kindREST, mds)
+}
+
+// SSE add Server-Sent Events endpoint with method GET.
+// Connection is kept open until callback returns or client is disconnected,
+// use stream.Done() to detect disconnection
+func (ep *Endpoints) SSE(path string, callback func(stream *EventStream), mds ...handleToHandle) {
+	ep.add("GET", path, callback, kindSSE, mds)
+}
+
+// SSEHeartbeat set interval of heartbeat comment sent to keep SSE connections alive.
+// Default is 15 seconds, set to negative value to disable heartbeat
+func (ep *Endpoints) SSEHeartbeat(interval time.Duration) {
+	ep.sseHeartbeat = interval
+}
+
+// WebSocket add WebSocket endpoint with method GET.
+// Middlewares are executed before the connection is upgraded,
+// and the connection is closed when handler returns
+func (ep *Endpoints) WebSocket(path string, handler func(conn *WebSocketConn), mds ...handleToHandle) {
+	ep.add("GET", path, handler, kindWebSocket, mds)
+}
+
+// WebSocketOptions set options for WebSocket endpoints
+func (ep *Endpoints) WebSocketOptions(opt WebSocketOption) {
+	ep.wsOption = &opt
+}
+
+// IfMatch enable If-Match precondition on PUT, PATCH and DELETE endpoints.
+// current return current entity tag of the requested resource, and request
+// with If-Match header that doesn't match it is rejected with 412 Precondition Failed
+// before callback is called. Request without If-Match header is not checked
+func (ep *Endpoints) IfMatch(current ETagFunc) {
+	ep.ifMatch = current
+}
+
+// Cache enable response cache on GET and HEAD endpoints.
+// Responses with status 200 are stored in cache, keyed by method, path, queries
+// and headers in opt.Vary. Responses with Cache-Control no-store, no-cache or private,
+// or with Set-Cookie header are never stored. Cache is checked after middlewares,
+// so authentication is still applied to cached responses
+func (ep *Endpoints) Cache(cache *ResponseCache, opt CacheOption) {
+	ep.cache = cache
+	ep.cacheOption = opt
+}
+
+// CORS set CORS options for endpoints, overriding options set by Minirest.CORS.
+// Preflight is answered per path, so registering endpoints of the same path
+// with different options panics
+func (ep *Endpoints) CORS(opt CORSOption) {
+	ep.cors = &opt
+}
+
+// AccessLog set access log options for endpoints, overriding options set by Minirest.AccessLog
+func (ep *Endpoints) AccessLog(opt AccessLogOption) {
+	ep.accessLog = newAccessLogger(opt)
+}
+
+// SkipAccessLog disable access log for endpoints, such as health checks
+func (ep *Endpoints) SkipAccessLog() {
+	ep.noAccessLog = true
+}
+
+// Version set API version of endpoints, such as "v1".
+// How the version is selected from request is set by Minirest.Versioning
+func (ep *Endpoints) Version(version string) {
+	ep.version = version
+}
+
+// Doc set documentation of endpoint with method and path, used when generating OpenAPI document.
+// path is relative to base path of ep, same as path passed when adding the endpoint
+func (ep *Endpoints) Doc(method, path string, doc EndpointDoc) {
+	if ep.docs == nil {
+		ep.docs = make(map[string]EndpointDoc)
+	}
+
+	ep.docs[method+" "+path] = doc
 }
 
 // Middlewares register middleware chain.
@@ -60,3 +175,101 @@ func (ep *Endpoints) Middlewares(mds ...handleToHandle) {
 
 	ep.middleware.handles = append(ep.middleware.handles, mds...)
 }
+
+// EndpointDoc is documentation of endpoint
+type EndpointDoc struct {
+	Summary     string
+	Description string
+	// Tags group endpoints in documentation, default is controller name
+	Tags       []string
+	Deprecated bool
+	// Response is value of the type put into response body, such as User{} or []User{}.
+	// Leave it nil when endpoint doesn't return data
+	Response interface{}
+	// Status is status code of successful response, default is 200
+	Status int
+	// Errors is status codes of error responses returned by endpoint
+	Errors []int
+}
+
+func (ep *Endpoints) add(method, path string, callback interface{}, kind int, mds []handleToHandle) {
+	ep.endpoints = append(ep.endpoints, endpoint{
+		method:      method,
+		path:        path,
+		callback:    callback,
+		kind:        kind,
+		middlewares: mds,
+	})
+}
+
+// routeScope is settings of Endpoints merged with settings of its parent groups
+type routeScope struct {
+	controller   string
+	basePath     string
+	middlewares  []handleToHandle
+	gzip         bool
+	sseHeartbeat time.Duration
+	wsOption     WebSocketOption
+	ifMatch      ETagFunc
+	cache        *ResponseCache
+	cacheOption  CacheOption
+	cors         *CORSOption
+	version      string
+	accessLog    *accessLogger
+	noAccessLog  bool
+}
+
+// inherit return scope of ep nested under parent.
+// Middlewares of parent are executed first, other settings of ep override parent
+func (parent routeScope) inherit(ep *Endpoints) routeScope {
+	scope := parent
+	scope.basePath = parent.basePath + ep.basePath
+	scope.gzip = parent.gzip || ep.Gzip
+	if ep.middleware != nil {
+		scope.middlewares = make([]handleToHandle, 0, len(parent.middlewares)+len(ep.middleware.handles))
+		scope.middlewares = append(scope.middlewares, parent.middlewares...)
+		scope.middlewares = append(scope.middlewares, ep.middleware.handles...)
+	}
+
+	if ep.sseHeartbeat != 0 {
+		scope.sseHeartbeat = ep.sseHeartbeat
+	}
+
+	if ep.wsOption != nil {
+		scope.wsOption = *ep.wsOption
+	}
+
+	if ep.ifMatch != nil {
+		scope.ifMatch = ep.ifMatch
+	}
+
+	if ep.cache != nil {
+		scope.cache = ep.cache
+		scope.cacheOption = ep.cacheOption
+	}
+
+	if ep.cors != nil {
+		scope.cors = ep.cors
+	}
+
+	if ep.version != "" {
+		scope.version = ep.version
+	}
+
+	if ep.accessLog != nil {
+		scope.accessLog = ep.accessLog
+	}
+
+	scope.noAccessLog = parent.noAccessLog || ep.noAccessLog
+
+	return scope
+}
+
+// chain wrap handle with middlewares of scope and route middlewares mds
+func (scope routeScope) chain(handle httprouter.Handle, mds []handleToHandle) httprouter.Handle {
+	chain := &handleChain{handles: make([]handleToHandle, 0, len(scope.middlewares)+len(mds))}
+	chain.handles = append(chain.handles, scope.middlewares...)
+	chain.handles = append(chain.handles, mds...)
+
+	return chain.handleChain(handle)
+}
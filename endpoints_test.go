@@ -0,0 +1,134 @@
+package minirest
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// trace return middleware that append name to X-Trace header before calling next
+func trace(name string) handleToHandle {
+	return func(next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+			w.Header().Add("X-Trace", name)
+			next(w, r, p)
+		}
+	}
+}
+
+func TestGroups(t *testing.T) {
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.BasePath("/api")
+		ep.Middlewares(trace("root"))
+		ep.GET("/health", func() *ResponseBuilder { return echo("ok") })
+
+		users := ep.Group("/users")
+		users.Middlewares(trace("users"))
+		users.GET("/profile/:id", func(id int) *ResponseBuilder { return echo(id) }, trace("route"), trace("route2"))
+
+		admin := users.Group("/admin")
+		admin.Middlewares(trace("admin"))
+		admin.GET("", func() *ResponseBuilder { return echo("admin") })
+
+		// group without middlewares inherit the parent's
+		ep.Group("/public").GET("/info", func() *ResponseBuilder { return echo("info") })
+	})
+
+	tests := []struct {
+		target string
+		trace  []string
+		body   interface{}
+	}{
+		{target: "/api/health", trace: []string{"root"}, body: "ok"},
+		{target: "/api/users/profile/7", trace: []string{"root", "users", "route", "route2"}, body: float64(7)},
+		{target: "/api/users/admin", trace: []string{"root", "users", "admin"}, body: "admin"},
+		{target: "/api/public/info", trace: []string{"root"}, body: "info"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			w := serve(mn, "GET", tt.target, nil)
+			if w.Code != 200 {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+
+			if got := w.Header()["X-Trace"]; !reflect.DeepEqual(got, tt.trace) {
+				t.Errorf("middlewares = %q, want %q", got, tt.trace)
+			}
+
+			if got := decodeBody(t, w); got != tt.body {
+				t.Errorf("body = %v, want %v", got, tt.body)
+			}
+		})
+	}
+
+	// route middlewares don't leak into other routes of the group
+	if w := serve(mn, "GET", "/api/users/admin", nil); strings.Contains(strings.Join(w.Header()["X-Trace"], ","), "route") {
+		t.Errorf("X-Trace = %q", w.Header()["X-Trace"])
+	}
+}
+
+func TestGroupMiddlewareShortCircuit(t *testing.T) {
+	auth := func(next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+			if r.Header.Get("Authorization") == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			next(w, r, p)
+		}
+	}
+
+	called := false
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		private := ep.Group("/private")
+		private.Middlewares(auth)
+		private.GET("/data", func() *ResponseBuilder {
+			called = true
+			return echo(1)
+		})
+		ep.GET("/public", func() *ResponseBuilder { return echo(1) })
+	})
+
+	if w := serve(mn, "GET", "/private/data", nil); w.Code != http.StatusUnauthorized || called {
+		t.Errorf("status = %d, callback called %v", w.Code, called)
+	}
+
+	if w := serve(mn, "GET", "/public", nil); w.Code != 200 {
+		t.Errorf("public status = %d", w.Code)
+	}
+}
+
+func TestGroupSettings(t *testing.T) {
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.Gzip = true
+		ep.GET("/gzip", func() *ResponseBuilder { return echo(1) })
+
+		// settings of parent are inherited
+		ep.Group("/child").GET("/gzip", func() *ResponseBuilder { return echo(1) })
+
+		// settings of group are not applied to parent
+		group := ep.Group("")
+		group.CORS(CORSOption{AllowOrigins: []string{"*"}})
+		group.GET("/cors", func() *ResponseBuilder { return echo(1) })
+	})
+
+	for _, target := range []string{"/gzip", "/child/gzip", "/cors"} {
+		r := corsRequest(mn, "GET", target, "https://a.example", "Accept-Encoding", "gzip")
+		if got := r.Header().Get("Content-Encoding"); got != "gzip" {
+			t.Errorf("%s Content-Encoding = %q, want gzip", target, got)
+		}
+	}
+
+	if got := corsRequest(mn, "GET", "/gzip", "https://a.example").Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Access-Control-Allow-Origin of parent = %q, want none", got)
+	}
+
+	if got := corsRequest(mn, "GET", "/cors", "https://a.example").Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin of group = %q", got)
+	}
+}
@@ -0,0 +1,251 @@
+package minirest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Envelope format Response built by ResponseBuilder helpers, such as Ok and BadRequest,
+// into the body written to client.
+// Response set with ResponseBuilder.Body is written as is and never formatted
+type Envelope interface {
+	// Format return content type and body of the response.
+	// Returning nil body will write response without body
+	Format(r *http.Request, resp Response) (contentType string, body interface{})
+}
+
+// EnvelopeFunc is adapter for using function as Envelope
+type EnvelopeFunc func(r *http.Request, resp Response) (contentType string, body interface{})
+
+// Format call f(r, resp)
+func (f EnvelopeFunc) Format(r *http.Request, resp Response) (string, interface{}) {
+	return f(r, resp)
+}
+
+// EnvelopeDecoder is implemented by Envelope that can decode the body it write back into Response,
+// used by minitest. All built-in envelopes implement it
+type EnvelopeDecoder interface {
+	// Decode return Response of body written with status code,
+	// Body of the returned Response is json.RawMessage or nil
+	Decode(code int, body []byte) (Response, error)
+}
+
+// DecodeResponse decode body written by env with status code into Response,
+// see EnvelopeDecoder. Nil env is DefaultEnvelope
+func DecodeResponse(env Envelope, code int, body []byte) (Response, error) {
+	if env == nil {
+		env = DefaultEnvelope
+	}
+
+	if len(body) == 0 {
+		return Response{StatusCode: code, Status: StatusMessage(code)}, nil
+	}
+
+	decoder, ok := env.(EnvelopeDecoder)
+	if !ok {
+		return Response{}, fmt.Errorf("minirest: envelope %T doesn't implement EnvelopeDecoder", env)
+	}
+
+	return decoder.Decode(code, body)
+}
+
+// rawBody return body as Response.Body, nil if it's empty
+func rawBody(body json.RawMessage) interface{} {
+	if len(body) == 0 {
+		return nil
+	}
+
+	return body
+}
+
+// Built-in envelopes. They are described in OpenAPI document by the shape they write,
+// responses of other envelopes are described as any JSON value
+var (
+	// DefaultEnvelope write Response as is
+	DefaultEnvelope Envelope = defaultEnvelope{}
+	// RawEnvelope write data without envelope.
+	// If there is no data, description is written instead
+	RawEnvelope Envelope = rawEnvelope{}
+	// JSONAPIEnvelope write response in JSON:API format
+	JSONAPIEnvelope Envelope = jsonAPIEnvelope{}
+)
+
+// Problem is RFC 7807 problem details
+type Problem struct {
+	Type     string      `json:"type"`
+	Title    string      `json:"title"`
+	Status   int         `json:"status"`
+	Detail   string      `json:"detail,omitempty"`
+	Instance string      `json:"instance,omitempty"`
+	Details  interface{} `json:"details,omitempty"`
+}
+
+// ProblemEnvelope write error responses (status code 400 and above) as RFC 7807
+// application/problem+json, other responses are formatted by success.
+// If success is nil, DefaultEnvelope is used
+func ProblemEnvelope(success Envelope) Envelope {
+	if success == nil {
+		success = DefaultEnvelope
+	}
+
+	return problemEnvelope{success: success}
+}
+
+type problemEnvelope struct {
+	success Envelope
+}
+
+func (env problemEnvelope) Format(r *http.Request, resp Response) (string, interface{}) {
+	if resp.StatusCode < 400 {
+		return env.success.Format(r, resp)
+	}
+
+	problem := Problem{
+		Type:    "about:blank",
+		Title:   http.StatusText(resp.StatusCode),
+		Status:  resp.StatusCode,
+		Detail:  resp.Description,
+		Details: resp.Body,
+	}
+
+	if r != nil {
+		problem.Instance = r.URL.Path
+	}
+
+	return "application/problem+json", problem
+}
+
+func (env problemEnvelope) Decode(code int, body []byte) (Response, error) {
+	if code < 400 {
+		return DecodeResponse(env.success, code, body)
+	}
+
+	var problem struct {
+		Detail  string          `json:"detail"`
+		Details json.RawMessage `json:"details"`
+	}
+
+	if err := json.Unmarshal(body, &problem); err != nil {
+		return Response{}, err
+	}
+
+	return Response{StatusCode: code, Status: StatusMessage(code), Description: problem.Detail, Body: rawBody(problem.Details)}, nil
+}
+
+type defaultEnvelope struct{}
+
+func (defaultEnvelope) Format(r *http.Request, resp Response) (string, interface{}) {
+	return "application/json", resp
+}
+
+func (defaultEnvelope) Decode(code int, body []byte) (Response, error) {
+	var env struct {
+		StatusCode  int             `json:"statusCode"`
+		Status      string          `json:"status"`
+		Description string          `json:"description"`
+		Body        json.RawMessage `json:"body"`
+	}
+
+	if err := json.Unmarshal(body, &env); err != nil {
+		return Response{}, err
+	}
+
+	return Response{StatusCode: env.StatusCode, Status: env.Status, Description: env.Description, Body: rawBody(env.Body)}, nil
+}
+
+type rawEnvelope struct{}
+
+// Decode put body into Body, and into Description too if it's string,
+// because description is written instead of missing data
+func (rawEnvelope) Decode(code int, body []byte) (Response, error) {
+	if !json.Valid(body) {
+		return Response{}, fmt.Errorf("minirest: body is not JSON")
+	}
+
+	resp := Response{StatusCode: code, Status: StatusMessage(code), Body: json.RawMessage(body)}
+	json.Unmarshal(body, &resp.Description)
+	return resp, nil
+}
+
+func (rawEnvelope) Format(r *http.Request, resp Response) (string, interface{}) {
+	if resp.Body != nil {
+		return "application/json", resp.Body
+	}
+
+	if resp.Description != "" {
+		return "application/json", resp.Description
+	}
+
+	return "application/json", nil
+}
+
+// JSON:API error object
+type jsonAPIError struct {
+	Status string      `json:"status"`
+	Code   string      `json:"code,omitempty"`
+	Detail string      `json:"detail,omitempty"`
+	Meta   interface{} `json:"meta,omitempty"`
+}
+
+type jsonAPIEnvelope struct{}
+
+func (jsonAPIEnvelope) Decode(code int, body []byte) (Response, error) {
+	var doc struct {
+		Data json.RawMessage `json:"data"`
+		Meta struct {
+			Description string `json:"description"`
+		} `json:"meta"`
+		Errors []struct {
+			Code   string          `json:"code"`
+			Detail string          `json:"detail"`
+			Meta   json.RawMessage `json:"meta"`
+		} `json:"errors"`
+	}
+
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return Response{}, err
+	}
+
+	resp := Response{StatusCode: code, Status: StatusMessage(code)}
+	if len(doc.Errors) > 0 {
+		resp.Status = doc.Errors[0].Code
+		resp.Description = doc.Errors[0].Detail
+		resp.Body = rawBody(doc.Errors[0].Meta)
+		return resp, nil
+	}
+
+	resp.Description = doc.Meta.Description
+	resp.Body = rawBody(doc.Data)
+	return resp, nil
+}
+
+func (jsonAPIEnvelope) Format(r *http.Request, resp Response) (string, interface{}) {
+	const contentType = "application/vnd.api+json"
+	if resp.StatusCode >= 400 {
+		return contentType, map[string]interface{}{
+			"errors": []jsonAPIError{{
+				Status: strconv.Itoa(resp.StatusCode),
+				Code:   resp.Status,
+				Detail: resp.Description,
+				Meta:   resp.Body,
+			}},
+		}
+	}
+
+	doc := make(map[string]interface{})
+	if resp.Body != nil {
+		doc["data"] = resp.Body
+	}
+
+	if resp.Description != "" {
+		doc["meta"] = map[string]string{"description": resp.Description}
+	}
+
+	if len(doc) == 0 {
+		return contentType, nil
+	}
+
+	return contentType, doc
+}
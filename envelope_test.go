@@ -0,0 +1,141 @@
+package minirest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestResponseEnvelope(t *testing.T) {
+	custom := EnvelopeFunc(func(r *http.Request, resp Response) (string, interface{}) {
+		return "application/x-custom+json", map[string]interface{}{
+			"ok":    resp.StatusCode < 400,
+			"path":  r.URL.Path,
+			"error": resp.Description,
+			"data":  resp.Body,
+		}
+	})
+
+	tests := []struct {
+		name        string
+		env         Envelope
+		target      string
+		body        string
+		status      int
+		contentType string
+		want        string
+	}{
+		{
+			name:        "default",
+			target:      "/users/1",
+			status:      200,
+			contentType: "application/json",
+			want:        `{"statusCode":200,"status":"ok","body":1}`,
+		},
+		{
+			name:        "custom",
+			env:         custom,
+			target:      "/users/1",
+			status:      200,
+			contentType: "application/x-custom+json",
+			want:        `{"data":1,"error":"","ok":true,"path":"/users/1"}`,
+		},
+		{
+			name:        "custom bind failure",
+			env:         custom,
+			target:      "/users/x",
+			status:      400,
+			contentType: "application/x-custom+json",
+			want:        `"ok":false,"path":"/users/x"`,
+		},
+		{
+			name:        "custom not found",
+			env:         custom,
+			target:      "/missing",
+			status:      404,
+			contentType: "application/x-custom+json",
+			want:        `{"data":null,"error":"path /missing not found","ok":false,"path":"/missing"}`,
+		},
+		{
+			name:        "raw",
+			env:         RawEnvelope,
+			target:      "/users/1",
+			status:      200,
+			contentType: "application/json",
+			want:        `1`,
+		},
+		{
+			name:        "raw keep body set by controller",
+			env:         RawEnvelope,
+			target:      "/users/0",
+			status:      200,
+			contentType: "application/json",
+			want:        `{"raw":true}`,
+		},
+		{
+			name:        "json api error",
+			env:         JSONAPIEnvelope,
+			target:      "/users/x",
+			status:      400,
+			contentType: "application/vnd.api+json",
+			want:        `{"errors":[{"status":"400","code":"bad_request","detail":`,
+		},
+		{
+			name:        "problem with raw success",
+			env:         ProblemEnvelope(RawEnvelope),
+			target:      "/users/1",
+			status:      200,
+			contentType: "application/json",
+			want:        `1`,
+		},
+		{
+			name:        "problem not found",
+			env:         ProblemEnvelope(RawEnvelope),
+			target:      "/missing",
+			status:      404,
+			contentType: "application/problem+json",
+			want:        `{"type":"about:blank","title":"Not Found","status":404,"detail":"path /missing not found","instance":"/missing"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mn := newTestApp("GET", "/users/:id", func(id int) *ResponseBuilder {
+				if id == 0 {
+					return new(ResponseBuilder).Status(200).Body(map[string]bool{"raw": true})
+				}
+
+				return echo(id)
+			})
+
+			if tt.env != nil {
+				mn.ResponseEnvelope(tt.env)
+			}
+
+			w := serve(mn, "GET", tt.target, nil)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+
+			if got := w.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+
+			if got := w.Body.String(); !strings.Contains(got, tt.want) {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseEnvelopeWithoutBody(t *testing.T) {
+	mn := newTestApp("GET", "/users/:id", func(id int) *ResponseBuilder {
+		return new(ResponseBuilder).Ok(nil)
+	})
+
+	mn.ResponseEnvelope(RawEnvelope)
+	w := serve(mn, "GET", "/users/1", nil)
+	if w.Code != 200 || w.Body.Len() != 0 || w.Header().Get("Content-Type") != "" {
+		t.Errorf("status = %d, body %q, Content-Type %q", w.Code, w.Body.String(), w.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"github.com/tamboto2000/minirest"
+)
+
+type User struct {
+	ID   int
+	Name string
+}
+
+type UserController struct{}
+
+func (ctrl *UserController) Get(id int) *minirest.ResponseBuilder {
+	return new(minirest.ResponseBuilder).Ok(User{ID: id, Name: "John"})
+}
+
+func (ctrl *UserController) Delete(id int) *minirest.ResponseBuilder {
+	return new(minirest.ResponseBuilder).NoContent("")
+}
+
+func (ctrl *UserController) Endpoints() *minirest.Endpoints {
+	// /api -> /v1 -> /users
+	api := new(minirest.Endpoints)
+	api.BasePath("/api")
+	api.Middlewares(Logger)
+
+	v1 := api.Group("/v1")
+	v1.Gzip = true
+
+	users := v1.Group("/users")
+	users.Middlewares(Auth)
+	users.GET("/:id", ctrl.Get)
+	// only admin can delete user
+	users.DELETE("/:id", ctrl.Delete, RequireAdmin)
+
+	return api
+}
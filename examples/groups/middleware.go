@@ -0,0 +1,37 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func Logger(next httprouter.Handle) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		log.Println(r.Method, r.URL.Path)
+		next(w, r, p)
+	})
+}
+
+func Auth(next httprouter.Handle) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r, p)
+	})
+}
+
+func RequireAdmin(next httprouter.Handle) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		if r.Header.Get("X-Role") != "admin" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		next(w, r, p)
+	})
+}
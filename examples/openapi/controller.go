@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/tamboto2000/minirest"
+)
+
+type User struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserFilter struct {
+	Name  string `schema:"name"`
+	Limit int    `schema:"limit"`
+}
+
+type UserController struct{}
+
+func (ctrl *UserController) List(filter UserFilter) *minirest.ResponseBuilder {
+	return new(minirest.ResponseBuilder).Ok([]User{{ID: 1, Name: "John"}})
+}
+
+func (ctrl *UserController) Get(id int) *minirest.ResponseBuilder {
+	return new(minirest.ResponseBuilder).Ok(User{ID: id, Name: "John"})
+}
+
+func (ctrl *UserController) Create(user User) *minirest.ResponseBuilder {
+	return new(minirest.ResponseBuilder).Created("/users/1", user)
+}
+
+func (ctrl *UserController) Endpoints() *minirest.Endpoints {
+	ep := new(minirest.Endpoints)
+	ep.BasePath("/users")
+	ep.GET("", ctrl.List)
+	ep.Doc("GET", "", minirest.EndpointDoc{Summary: "List users", Response: []User{}})
+	ep.GET("/:id", ctrl.Get)
+	ep.Doc("GET", "/:id", minirest.EndpointDoc{Summary: "Get user", Response: User{}, Errors: []int{http.StatusNotFound}})
+	ep.POST("", ctrl.Create)
+	ep.Doc("POST", "", minirest.EndpointDoc{Summary: "Create user", Response: User{}, Status: http.StatusCreated})
+
+	return ep
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"github.com/tamboto2000/minirest"
+)
+
+func main() {
+	mns := minirest.New()
+	mns.AddController(new(UserController))
+	// document is served at /openapi.json and docs page at /docs
+	mns.OpenAPI(minirest.OpenAPIOption{Title: "User API", Version: "1.0.0"})
+	mns.ServePort("8081")
+	mns.RunServer()
+}
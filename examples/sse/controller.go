@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/tamboto2000/minirest"
+)
+
+type StatusController struct{}
+
+func (ctrl *StatusController) Status(stream *minirest.EventStream) {
+	// resume from the last event received by client
+	id, _ := strconv.Atoi(stream.LastEventID())
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stream.Done():
+			return
+		case t := <-ticker.C:
+			id++
+			err := stream.Send(minirest.Event{
+				ID:    strconv.Itoa(id),
+				Event: "status",
+				Data:  map[string]interface{}{"time": t, "healthy": true},
+			})
+
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (ctrl *StatusController) Endpoints() *minirest.Endpoints {
+	endpoints := new(minirest.Endpoints)
+	endpoints.BasePath("/status")
+	endpoints.SSEHeartbeat(10 * time.Second)
+	endpoints.SSE("/stream", ctrl.Status)
+
+	return endpoints
+}
@@ -0,0 +1,12 @@
+package main
+
+import (
+	"github.com/tamboto2000/minirest"
+)
+
+func main() {
+	mns := minirest.New()
+	mns.AddController(new(StatusController))
+	mns.ServePort("8081")
+	mns.RunServer()
+}
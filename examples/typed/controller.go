@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/tamboto2000/minirest"
+)
+
+type User struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type GetUser struct {
+	ID int `path:"id"`
+}
+
+type ListUsers struct {
+	Name  string `query:"name"`
+	Limit int    `query:"limit"`
+}
+
+type UpdateUser struct {
+	ID   int    `path:"id"`
+	Name string `json:"name"`
+}
+
+type UserController struct {
+	users map[int]User
+}
+
+func (ctrl *UserController) List(ctx context.Context, req ListUsers) ([]User, error) {
+	var users []User
+	for _, user := range ctrl.users {
+		if req.Name == "" || user.Name == req.Name {
+			users = append(users, user)
+		}
+	}
+
+	return users, nil
+}
+
+func (ctrl *UserController) Get(ctx context.Context, req GetUser) (User, error) {
+	user, ok := ctrl.users[req.ID]
+	if !ok {
+		return User{}, minirest.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+
+	return user, nil
+}
+
+func (ctrl *UserController) Update(ctx context.Context, req UpdateUser) (User, error) {
+	if _, ok := ctrl.users[req.ID]; !ok {
+		return User{}, minirest.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+
+	user := User{ID: req.ID, Name: req.Name}
+	ctrl.users[req.ID] = user
+
+	return user, nil
+}
+
+func (ctrl *UserController) Endpoints() *minirest.Endpoints {
+	ep := new(minirest.Endpoints)
+	ep.BasePath("/users")
+	minirest.Handle(ep, "GET", "", ctrl.List)
+	minirest.Handle(ep, "GET", "/:id", ctrl.Get)
+	minirest.Handle(ep, "PUT", "/:id", ctrl.Update)
+
+	return ep
+}
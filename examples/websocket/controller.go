@@ -0,0 +1,42 @@
+package main
+
+import (
+	"time"
+
+	"github.com/tamboto2000/minirest"
+)
+
+type Message struct {
+	Room string `json:"room"`
+	Text string `json:"text"`
+}
+
+type ChatController struct{}
+
+func (ctrl *ChatController) Chat(conn *minirest.WebSocketConn) {
+	room := conn.Params().ByName("room")
+	for {
+		var msg Message
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+
+		msg.Room = room
+		if err := conn.WriteJSON(msg); err != nil {
+			return
+		}
+	}
+}
+
+func (ctrl *ChatController) Endpoints() *minirest.Endpoints {
+	endpoints := new(minirest.Endpoints)
+	endpoints.BasePath("/chat")
+	endpoints.Middlewares(Auth)
+	endpoints.WebSocketOptions(minirest.WebSocketOption{
+		ReadLimit:    4096,
+		PingInterval: 20 * time.Second,
+	})
+	endpoints.WebSocket("/:room", ctrl.Chat)
+
+	return endpoints
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/tamboto2000/minirest"
+)
+
+func main() {
+	mns := minirest.New()
+	mns.AddController(new(ChatController))
+	mns.ServePort("8081")
+
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt)
+		<-sig
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		mns.Shutdown(ctx)
+	}()
+
+	mns.RunServer()
+}
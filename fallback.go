@@ -0,0 +1,63 @@
+package minirest
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// NotFoundHandler set handler for request that doesn't match any route.
+// Default handler write ResponseBuilder.NotFound, set to nil for restoring it.
+// CORS headers set by Minirest.CORS are added before handler is called, as for
+// the other fallback handlers
+func (mn *Minirest) NotFoundHandler(handler http.Handler) {
+	if handler == nil {
+		handler = http.HandlerFunc(mn.notFound)
+	}
+
+	mn.router.NotFound = mn.withFallbackCORS(handler)
+}
+
+// MethodNotAllowedHandler set handler for request with method that isn't registered for the path.
+// Allow header is set before handler is called.
+// Default handler write ResponseBuilder.MethodNotAllowed, set to nil for restoring it
+func (mn *Minirest) MethodNotAllowedHandler(handler http.Handler) {
+	if handler == nil {
+		handler = http.HandlerFunc(mn.methodNotAllowed)
+	}
+
+	mn.router.MethodNotAllowed = mn.withFallbackCORS(handler)
+}
+
+// PanicHandler set handler for recovering panic in handlers, rcv is the recovered value.
+// Default handler log the panic and write ResponseBuilder.InternalError, set to nil for restoring it
+func (mn *Minirest) PanicHandler(handler func(w http.ResponseWriter, r *http.Request, rcv interface{})) {
+	if handler == nil {
+		handler = mn.panicHandler
+	}
+
+	mn.router.PanicHandler = func(w http.ResponseWriter, r *http.Request, rcv interface{}) {
+		mn.fallbackCORS(w, r)
+		handler(w, r, rcv)
+	}
+}
+
+func (mn *Minirest) withFallbackCORS(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mn.fallbackCORS(w, r)
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func (mn *Minirest) notFound(w http.ResponseWriter, r *http.Request) {
+	mn.writeResponse(w, r, new(ResponseBuilder).NotFound("path "+r.URL.Path+" not found"))
+}
+
+func (mn *Minirest) methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	mn.writeResponse(w, r, new(ResponseBuilder).MethodNotAllowed("method "+r.Method+" is not allowed"))
+}
+
+func (mn *Minirest) panicHandler(w http.ResponseWriter, r *http.Request, rcv interface{}) {
+	mn.logRequest(r, slog.LevelError, "minirest: panic recovered", "panic", rcv, "stack", string(debug.Stack()))
+	mn.writeResponse(w, r, new(ResponseBuilder).InternalError("internal server error"))
+}
@@ -0,0 +1,103 @@
+package minirest
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestFallbackResponses(t *testing.T) {
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.GET("/items", func() *ResponseBuilder { return echo(1) })
+		ep.POST("/items", func(body testBody) *ResponseBuilder { return echo(body) })
+		ep.GET("/panic", func() *ResponseBuilder { panic("boom") })
+	})
+
+	mn.Logger = NopLogger
+	tests := []struct {
+		name   string
+		method string
+		target string
+		status int
+		want   string
+	}{
+		{name: "not found", method: "GET", target: "/missing", status: 404, want: `{"statusCode":404,"status":"not_found","description":"path /missing not found"}`},
+		{name: "method not allowed", method: "DELETE", target: "/items", status: 405, want: `{"statusCode":405,"status":"method_not_allowed","description":"method DELETE is not allowed"}`},
+		{name: "panic", method: "GET", target: "/panic", status: 500, want: `{"statusCode":500,"status":"internal_error","description":"internal server error"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(mn, tt.method, tt.target, nil)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+
+			if got := w.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q", got)
+			}
+
+			if got := w.Body.String(); got != tt.want+"\n" {
+				t.Errorf("body = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if got := serve(mn, "DELETE", "/items", nil).Header().Get("Allow"); got != "GET, OPTIONS, POST" {
+		t.Errorf("Allow = %q", got)
+	}
+}
+
+func TestFallbackHandlers(t *testing.T) {
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.GET("/items", func() *ResponseBuilder { return echo(1) })
+		ep.GET("/panic", func() *ResponseBuilder { panic("boom") })
+	})
+
+	status := func(code int) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(code) })
+	}
+
+	var recovered interface{}
+	mn.NotFoundHandler(status(http.StatusTeapot))
+	mn.MethodNotAllowedHandler(status(http.StatusConflict))
+	mn.PanicHandler(func(w http.ResponseWriter, r *http.Request, rcv interface{}) {
+		recovered = rcv
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	if w := serve(mn, "GET", "/missing", nil); w.Code != http.StatusTeapot {
+		t.Errorf("not found status = %d", w.Code)
+	}
+
+	if w := serve(mn, "PUT", "/items", nil); w.Code != http.StatusConflict || w.Header().Get("Allow") == "" {
+		t.Errorf("method not allowed status = %d, Allow %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	if w := serve(mn, "GET", "/panic", nil); w.Code != http.StatusBadGateway || recovered != "boom" {
+		t.Errorf("panic status = %d, recovered %v", w.Code, recovered)
+	}
+
+	// nil restore the default handlers
+	mn.Logger = NopLogger
+	mn.NotFoundHandler(nil)
+	mn.MethodNotAllowedHandler(nil)
+	mn.PanicHandler(nil)
+	for _, tt := range []struct {
+		method, target string
+		status         int
+	}{{"GET", "/missing", 404}, {"PUT", "/items", 405}, {"GET", "/panic", 500}} {
+		if w := serve(mn, tt.method, tt.target, nil); w.Code != tt.status {
+			t.Errorf("%s %s status = %d, want %d", tt.method, tt.target, w.Code, tt.status)
+		}
+	}
+}
+
+func TestFallbackEnvelope(t *testing.T) {
+	mn := newTestApp("GET", "/items", func() *ResponseBuilder { return echo(1) })
+	mn.ResponseEnvelope(ProblemEnvelope(nil))
+
+	w := serve(mn, "PUT", "/items", nil)
+	if w.Code != 405 || w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Errorf("status = %d, Content-Type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+}
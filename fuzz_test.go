@@ -0,0 +1,89 @@
+package minirest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// responses of fuzzed requests must be either handled or rejected with 400, never 500
+
+func FuzzPathParams(f *testing.F) {
+	for _, seed := range []string{"1", "-1", "0", "1.5", "abc", "9999999999999999999999", "%00", "+3", " 4", "NaN", "-Inf", "infinity", "1e400"} {
+		f.Add(seed, seed)
+	}
+
+	mn := New()
+	mn.AddController(&testController{
+		method:   "GET",
+		path:     "/items/:id/:price",
+		callback: func(id int, price float64) *ResponseBuilder { return echo([]interface{}{id, price}) },
+	})
+
+	f.Fuzz(func(t *testing.T, id, price string) {
+		// router match decoded path, so escaped slash is another route
+		if id == "" || price == "" || strings.Contains(id+price, "/") {
+			return
+		}
+
+		target := "/items/" + url.PathEscape(id) + "/" + url.PathEscape(price)
+		w := serve(mn, "GET", target, nil)
+		_, idErr := strconv.Atoi(id)
+		_, priceErr := strconv.ParseFloat(price, 64)
+		switch {
+		case idErr == nil && priceErr == nil:
+			// ParseFloat accept NaN and Inf, which can't be encoded to JSON
+			if w.Code != http.StatusOK && w.Code != http.StatusInternalServerError {
+				t.Fatalf("%s: status = %d, body %s", target, w.Code, w.Body)
+			}
+		case w.Code != http.StatusBadRequest:
+			t.Fatalf("%s: status = %d, want 400, body %s", target, w.Code, w.Body)
+		}
+	})
+}
+
+func FuzzQueryDecoding(f *testing.F) {
+	for _, seed := range []string{"name=a&limit=1", "limit=x", "limit=1&limit=2", "name", "%zz", "color=red", "", "limit=-0"} {
+		f.Add(seed)
+	}
+
+	mn := New()
+	mn.AddController(&testController{
+		method:   "GET",
+		path:     "/items",
+		callback: func(q *testQuery) *ResponseBuilder { return echo(q) },
+	})
+
+	f.Fuzz(func(t *testing.T, query string) {
+		r := httptest.NewRequest("GET", "/items", nil)
+		r.URL.RawQuery = query
+		w := httptest.NewRecorder()
+		mn.ServeHTTP(w, r)
+		if w.Code != http.StatusOK && w.Code != http.StatusBadRequest {
+			t.Fatalf("query %q: status = %d, body %s", query, w.Code, w.Body)
+		}
+	})
+}
+
+func FuzzJSONBody(f *testing.F) {
+	for _, seed := range []string{`{"name":"a","age":1}`, `{}`, `null`, `[]`, `{"age":"x"}`, `{"name":`, ``, `{"age":1e400}`} {
+		f.Add([]byte(seed))
+	}
+
+	mn := New()
+	mn.AddController(&testController{
+		method:   "POST",
+		path:     "/items",
+		callback: func(b *testBody) *ResponseBuilder { return echo(b) },
+	})
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		w := serve(mn, "POST", "/items", strings.NewReader(string(body)))
+		if w.Code != http.StatusOK && w.Code != http.StatusBadRequest {
+			t.Fatalf("body %q: status = %d, response %s", body, w.Code, w.Body)
+		}
+	})
+}
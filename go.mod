@@ -1,6 +1,6 @@
 module github.com/tamboto2000/minirest
 
-go 1.14
+go 1.21
 
 require (
 	github.com/gorilla/schema v1.2.0
@@ -2,38 +2,70 @@ package minirest
 
 import (
 	"compress/gzip"
-	"io"
 	"net/http"
 
 	"github.com/julienschmidt/httprouter"
 )
 
+// gzipResponseWriter compress body written by handler.
+// The gzip stream is only started on the first Write, so bodiless
+// responses, such as 204, 304 and responses to HEAD request, stay empty
 type gzipResponseWriter struct {
-	io.Writer
 	http.ResponseWriter
+	gz *gzip.Writer
 }
 
-// use the Writer part of gzipResponseWriter to write the output.
-func (w gzipResponseWriter) Write(b []byte) (int, error) {
-	return w.Writer.Write(b)
+// WriteHeader drop Content-Encoding for status without body
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	if !bodyAllowedForStatus(code) {
+		w.Header().Del("Content-Encoding")
+	}
+
+	// length of uncompressed body is no longer valid
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// use the gzip writer to write the output.
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if w.gz == nil {
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+
+	return w.gz.Write(b)
+}
+
+// Unwrap return the underlying http.ResponseWriter
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *gzipResponseWriter) close() {
+	if w.gz != nil {
+		w.gz.Close()
+	}
 }
 
 func makeGzipHandler(fn httprouter.Handle) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 		// set the HTTP header indicating encoding.
 		w.Header().Set("Content-Encoding", "gzip")
-		gz := gzip.NewWriter(w)
-		defer gz.Close()
-		fn(gzipResponseWriter{Writer: gz, ResponseWriter: w}, r, p)
+		markGzip(w)
+		gzw := &gzipResponseWriter{ResponseWriter: w}
+		defer gzw.close()
+		fn(gzw, r, p)
 	}
 }
 
-func writeGzipResp(w http.ResponseWriter, data []byte, status int) {
+func writeGzipResp(w http.ResponseWriter, data []byte, status int) error {
 	w.Header().Set("Content-Encoding", "gzip")
+	markGzip(w)
 	w.WriteHeader(status)
 	gz := gzip.NewWriter(w)
-	defer gz.Close()
-	gz.Write(data)
+	if _, err := gz.Write(data); err != nil {
+		gz.Close()
+		return err
+	}
 
-	return
+	return gz.Close()
 }
@@ -0,0 +1,158 @@
+package minirest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func gunzip(t *testing.T, data []byte) string {
+	t.Helper()
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("body is not gzip encoded: %v", err)
+	}
+
+	raw, err := ioutil.ReadAll(gr)
+	if err != nil {
+		t.Fatalf("decode gzip body: %v", err)
+	}
+
+	return string(raw)
+}
+
+func TestGzipHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		resp     func() *ResponseBuilder
+		status   int
+		encoding string
+		body     string
+	}{
+		{
+			name:     "envelope",
+			method:   "GET",
+			resp:     func() *ResponseBuilder { return new(ResponseBuilder).Ok("hi") },
+			status:   200,
+			encoding: "gzip",
+			body:     `{"statusCode":200,"status":"ok","body":"hi"}` + "\n",
+		},
+		{
+			name:     "error",
+			method:   "GET",
+			resp:     func() *ResponseBuilder { return new(ResponseBuilder).NotFound("missing") },
+			status:   404,
+			encoding: "gzip",
+			body:     `{"statusCode":404,"status":"not_found","description":"missing"}` + "\n",
+		},
+		{
+			name:   "no content",
+			method: "GET",
+			resp:   func() *ResponseBuilder { return new(ResponseBuilder).NoContent("") },
+			status: 204,
+		},
+		{
+			name:     "head",
+			method:   "HEAD",
+			resp:     func() *ResponseBuilder { return new(ResponseBuilder).Ok("hi") },
+			status:   200,
+			encoding: "gzip",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handle := makeGzipHandler(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+				tt.resp().write(w, r, nil)
+			})
+
+			w := httptest.NewRecorder()
+			handle(w, httptest.NewRequest(tt.method, "/", nil), nil)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+
+			if got := w.Header().Get("Content-Encoding"); got != tt.encoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.encoding)
+			}
+
+			if got := w.Header().Get("Content-Length"); got != "" {
+				t.Errorf("Content-Length = %q, want not set", got)
+			}
+
+			if tt.body == "" {
+				if w.Body.Len() != 0 {
+					t.Errorf("body = %q, want empty", w.Body)
+				}
+
+				return
+			}
+
+			if got := gunzip(t, w.Body.Bytes()); got != tt.body {
+				t.Errorf("body = %q, want %q", got, tt.body)
+			}
+		})
+	}
+}
+
+func TestGzipResponseWriterLazy(t *testing.T) {
+	w := httptest.NewRecorder()
+	gzw := &gzipResponseWriter{ResponseWriter: w}
+	gzw.WriteHeader(http.StatusNotModified)
+	gzw.close()
+	if gzw.gz != nil || w.Body.Len() != 0 {
+		t.Errorf("gzip stream started without Write, body %q", w.Body)
+	}
+}
+
+func TestWriteGzipResp(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeGzipResp(w, []byte(`{"a":1}`), http.StatusCreated)
+	if w.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusCreated)
+	}
+
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Errorf("Content-Encoding = %q, want gzip", got)
+	}
+
+	if got := gunzip(t, w.Body.Bytes()); got != `{"a":1}` {
+		t.Errorf("body = %q", got)
+	}
+}
+
+func TestResponseBuilderGzip(t *testing.T) {
+	resp := new(ResponseBuilder).Ok("hi")
+	resp.Gzip = true
+	w := httptest.NewRecorder()
+	resp.write(w, httptest.NewRequest("GET", "/", nil), nil)
+
+	// gzip encoded body has no trailing newline
+	if got := gunzip(t, w.Body.Bytes()); got != `{"statusCode":200,"status":"ok","body":"hi"}` {
+		t.Errorf("body = %q", got)
+	}
+}
+
+func TestGzipEndpoints(t *testing.T) {
+	mn := New()
+	mn.Gzip = true
+	mn.AddController(&testController{method: "GET", path: "/items/:id", callback: func(id int) *ResponseBuilder { return echo(id) }})
+	w := serve(mn, "GET", "/items/1", nil)
+	if got := gunzip(t, w.Body.Bytes()); got != `{"statusCode":200,"status":"ok","body":1}`+"\n" {
+		t.Errorf("body = %q", got)
+	}
+
+	// bind errors are written through the same writer
+	w = serve(mn, "GET", "/items/x", nil)
+	if w.Code != 400 || w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("status = %d, Content-Encoding %q", w.Code, w.Header().Get("Content-Encoding"))
+	}
+
+	gunzip(t, w.Body.Bytes())
+}
@@ -0,0 +1,54 @@
+// Package clientcmd implement client generation shared by clientgen and minirest gen client commands
+package clientcmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/tamboto2000/minirest/clientgen"
+	"github.com/tamboto2000/minirest/openapi"
+)
+
+// Run parse flags of command name from args, load OpenAPI document from -spec or -app
+// and write generated client to -o, or stdout if -o is empty
+func Run(name string, args []string) error {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	spec := flags.String("spec", "", "OpenAPI document file or URL")
+	app := flags.String("app", "", "package of minirest app, built and run to write its document")
+	pkg := flags.String("pkg", "client", "package name of generated client")
+	out := flags.String("o", "", "output file, default is stdout")
+	timeout := flags.Duration("timeout", clientgen.LoadTimeout, "time limit of building and running -app")
+	flags.Parse(args)
+
+	if (*spec == "") == (*app == "") {
+		return fmt.Errorf("exactly one of -spec and -app is required")
+	}
+
+	var doc *openapi.Document
+	var err error
+	if *app != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		doc, err = clientgen.LoadAppContext(ctx, *app)
+	} else {
+		doc, err = clientgen.LoadSpec(*spec)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	src, err := clientgen.Generate(doc, clientgen.Options{Package: *pkg})
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+
+	return os.WriteFile(*out, src, 0644)
+}
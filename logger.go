@@ -0,0 +1,67 @@
+package minirest
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+)
+
+// Logger log internal messages of Minirest, such as recovered panics and response encoding errors.
+// args are key-value pairs, as in slog.Logger.Log, so *slog.Logger can be used as Logger
+type Logger interface {
+	Log(ctx context.Context, level slog.Level, msg string, args ...any)
+}
+
+// NopLogger discard all messages, such as for silencing Minirest in tests
+var NopLogger Logger = nopLogger{}
+
+type nopLogger struct{}
+
+func (nopLogger) Log(ctx context.Context, level slog.Level, msg string, args ...any) {}
+
+// SlogLogger create Logger writing messages into handler h
+func SlogLogger(h slog.Handler) Logger {
+	return slog.New(h)
+}
+
+type logFieldsKey struct{}
+
+// LogFields return ctx with request-scoped fields, key-value pairs as in slog.Logger.Log,
+// that are attached to messages logged while handling the request. Example in middleware:
+//
+//	r = r.WithContext(minirest.LogFields(r.Context(), "user_id", userID))
+func LogFields(ctx context.Context, args ...any) context.Context {
+	fields, _ := ctx.Value(logFieldsKey{}).([]any)
+	fields = append(fields[:len(fields):len(fields)], args...)
+
+	return context.WithValue(ctx, logFieldsKey{}, fields)
+}
+
+// log log message with Logger of mn, slog.Default() if Logger is nil
+func (mn *Minirest) log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	logger := mn.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	logger.Log(ctx, level, msg, args...)
+}
+
+// logRequest log message of request r, with method, path, request ID and fields set by LogFields
+func (mn *Minirest) logRequest(r *http.Request, level slog.Level, msg string, args ...any) {
+	fields := []any{"method", r.Method, "path", r.URL.Path}
+	header := "X-Request-ID"
+	if mn.accessLog != nil {
+		header = mn.accessLog.opt.RequestIDHeader
+	}
+
+	if id := r.Header.Get(header); id != "" {
+		fields = append(fields, "request_id", id)
+	}
+
+	if scoped, ok := r.Context().Value(logFieldsKey{}).([]any); ok {
+		fields = append(fields, scoped...)
+	}
+
+	mn.log(r.Context(), level, msg, append(fields, args...)...)
+}
@@ -0,0 +1,106 @@
+package minirest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestLogger(t *testing.T) {
+	buf := new(bytes.Buffer)
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.Middlewares(func(next httprouter.Handle) httprouter.Handle {
+			return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+				next(w, r.WithContext(LogFields(r.Context(), "user_id", 7)), p)
+			}
+		})
+
+		Handle(ep, "GET", "/panic", func(ctx context.Context, req struct{}) (string, error) {
+			panic("boom")
+		})
+		Handle(ep, "GET", "/error", func(ctx context.Context, req struct{}) (string, error) {
+			return "", errors.New("database is down")
+		})
+		Handle(ep, "GET", "/encode", func(ctx context.Context, req struct{}) (func(), error) {
+			return func() {}, nil
+		})
+	})
+	mn.Logger = SlogLogger(slog.NewJSONHandler(buf, nil))
+
+	tests := []struct {
+		path string
+		want map[string]interface{}
+	}{
+		{
+			path: "/panic",
+			// PanicHandler receive request before middlewares, so it has no fields set by them
+			want: map[string]interface{}{"msg": "minirest: panic recovered", "panic": "boom"},
+		},
+		{
+			path: "/error",
+			want: map[string]interface{}{"msg": "minirest: handler failed", "error": "database is down", "user_id": float64(7)},
+		},
+		{
+			path: "/encode",
+			want: map[string]interface{}{"msg": "minirest: write response", "error": "json: unsupported type: func()"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			buf.Reset()
+			r := httptest.NewRequest("GET", tt.path, nil)
+			r.Header.Set("X-Request-ID", "req-1")
+			w := httptest.NewRecorder()
+			mn.ServeHTTP(w, r)
+			if w.Code != http.StatusInternalServerError {
+				t.Errorf("status = %d", w.Code)
+			}
+
+			lines := logLines(t, buf)
+			if len(lines) != 1 {
+				t.Fatalf("got %d logs", len(lines))
+			}
+
+			tt.want["level"] = "ERROR"
+			tt.want["method"] = "GET"
+			tt.want["path"] = tt.path
+			tt.want["request_id"] = "req-1"
+			for key, value := range tt.want {
+				if lines[0][key] != value {
+					t.Errorf("%s = %v, want %v", key, lines[0][key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestLogFields(t *testing.T) {
+	ctx := LogFields(context.Background(), "a", 1)
+	first := LogFields(ctx, "b", 2)
+	second := LogFields(ctx, "c", 3)
+
+	fields := first.Value(logFieldsKey{}).([]any)
+	if len(fields) != 4 || fields[2] != "b" {
+		t.Errorf("first fields = %v", fields)
+	}
+
+	fields = second.Value(logFieldsKey{}).([]any)
+	if len(fields) != 4 || fields[2] != "c" {
+		t.Errorf("second fields = %v", fields)
+	}
+}
+
+func TestNopLogger(t *testing.T) {
+	mn := newTestApp("GET", "/panic", func() *ResponseBuilder { panic("boom") })
+	mn.Logger = NopLogger
+	if w := serve(mn, "GET", "/panic", nil); w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d", w.Code)
+	}
+}
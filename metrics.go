@@ -0,0 +1,322 @@
+package minirest
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// DefaultDurationBuckets is buckets of request duration histogram in seconds,
+// the same as default buckets of Prometheus client libraries
+var DefaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefaultSizeBuckets is buckets of request and response size histograms in bytes
+var DefaultSizeBuckets = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+
+// MetricsOption set options for metrics
+type MetricsOption struct {
+	// Path is path metrics are served on, default is /metrics
+	Path string
+	// Namespace is prefix of metric names, default is minirest
+	Namespace string
+	// DurationBuckets is upper bounds of request duration histogram, default is DefaultDurationBuckets
+	DurationBuckets []float64
+	// SizeBuckets is upper bounds of request and response size histograms, default is DefaultSizeBuckets
+	SizeBuckets []float64
+}
+
+// Metrics enable metrics of all routes, served on opt.Path in Prometheus text format.
+// Requests are labelled by registered route pattern, such as /users/:id, method and status,
+// requests that don't match any route are not counted.
+// Metrics are read on each request, so Metrics can be called after AddController.
+// Metrics may be called only once, it panics if metrics are already enabled
+func (mn *Minirest) Metrics(opt MetricsOption) {
+	if mn.metrics != nil {
+		panic("minirest: Metrics already enabled")
+	}
+
+	if opt.Path == "" {
+		opt.Path = "/metrics"
+	}
+
+	if opt.Namespace == "" {
+		opt.Namespace = "minirest"
+	}
+
+	if opt.DurationBuckets == nil {
+		opt.DurationBuckets = DefaultDurationBuckets
+	}
+
+	if opt.SizeBuckets == nil {
+		opt.SizeBuckets = DefaultSizeBuckets
+	}
+
+	m := newMetrics(opt)
+	mn.metrics = m
+	mn.Handler(http.MethodGet, opt.Path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.write(w)
+	}))
+}
+
+type metrics struct {
+	mu       sync.Mutex
+	inFlight int64
+	prefix   string
+	requests *metricFamily
+	duration *metricFamily
+	reqSize  *metricFamily
+	respSize *metricFamily
+	binds    *metricFamily
+	panics   *metricFamily
+	gzips    *metricFamily
+}
+
+func newMetrics(opt MetricsOption) *metrics {
+	prefix := opt.Namespace + "_"
+	return &metrics{
+		prefix:   prefix,
+		requests: newMetricFamily(prefix+"http_requests_total", "Total number of HTTP requests.", "counter", nil, "method", "route", "status"),
+		duration: newMetricFamily(prefix+"http_request_duration_seconds", "Duration of HTTP requests in seconds.", "histogram", opt.DurationBuckets, "method", "route", "status"),
+		reqSize:  newMetricFamily(prefix+"http_request_size_bytes", "Size of HTTP request bodies in bytes.", "histogram", opt.SizeBuckets, "method", "route"),
+		respSize: newMetricFamily(prefix+"http_response_size_bytes", "Size of HTTP response bodies in bytes, as written to the connection.", "histogram", opt.SizeBuckets, "method", "route", "status"),
+		binds:    newMetricFamily(prefix+"bind_failures_total", "Total number of requests that failed to bind.", "counter", nil, "method", "route"),
+		panics:   newMetricFamily(prefix+"panics_total", "Total number of recovered panics.", "counter", nil, "method", "route"),
+		gzips:    newMetricFamily(prefix+"gzip_responses_total", "Total number of gzip encoded responses.", "counter", nil, "method", "route"),
+	}
+}
+
+// observe record finished request to route
+func (m *metrics) observe(method, route string, w *metricsWriter, reqSize int64, duration time.Duration) {
+	status := strconv.Itoa(w.Status())
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests.with(method, route, status).add(1)
+	m.duration.with(method, route, status).observe(duration.Seconds())
+	m.reqSize.with(method, route).observe(float64(reqSize))
+	m.respSize.with(method, route, status).observe(float64(w.bytes))
+	if w.bindFailed {
+		m.binds.with(method, route).add(1)
+	}
+
+	if w.panicked {
+		m.panics.with(method, route).add(1)
+	}
+
+	if w.gzip {
+		m.gzips.with(method, route).add(1)
+	}
+}
+
+// write write all metrics into w in Prometheus text format
+func (m *metrics) write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	name := m.prefix + "http_requests_in_flight"
+	bw.WriteString("# HELP " + name + " Number of HTTP requests being served.\n")
+	bw.WriteString("# TYPE " + name + " gauge\n")
+	bw.WriteString(name + " " + strconv.FormatInt(atomic.LoadInt64(&m.inFlight), 10) + "\n")
+
+	m.mu.Lock()
+	for _, family := range []*metricFamily{m.requests, m.duration, m.reqSize, m.respSize, m.binds, m.panics, m.gzips} {
+		family.writeTo(bw)
+	}
+	m.mu.Unlock()
+
+	return bw.Flush()
+}
+
+// metricFamily is metric with all its series, keyed by label values
+type metricFamily struct {
+	name    string
+	help    string
+	typ     string
+	labels  []string
+	buckets []float64
+	series  map[string]*metricSeries
+}
+
+type metricSeries struct {
+	labels  string
+	value   float64
+	buckets []float64
+	counts  []uint64
+	count   uint64
+}
+
+func newMetricFamily(name, help, typ string, buckets []float64, labels ...string) *metricFamily {
+	return &metricFamily{name: name, help: help, typ: typ, labels: labels, buckets: buckets, series: make(map[string]*metricSeries)}
+}
+
+// with return series of label values, created if it doesn't exist
+func (f *metricFamily) with(values ...string) *metricSeries {
+	key := strings.Join(values, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		pairs := make([]string, len(values))
+		for i, value := range values {
+			pairs[i] = f.labels[i] + `="` + escapeLabel(value) + `"`
+		}
+
+		s = &metricSeries{labels: strings.Join(pairs, ","), buckets: f.buckets, counts: make([]uint64, len(f.buckets))}
+		f.series[key] = s
+	}
+
+	return s
+}
+
+// add add v into counter
+func (s *metricSeries) add(v float64) {
+	s.value += v
+}
+
+// observe add v into histogram, s.value is the sum of observed values
+func (s *metricSeries) observe(v float64) {
+	s.value += v
+	s.count++
+	for i, bound := range s.buckets {
+		if v <= bound {
+			s.counts[i]++
+		}
+	}
+}
+
+func (f *metricFamily) writeTo(w *bufio.Writer) {
+	if len(f.series) == 0 {
+		return
+	}
+
+	w.WriteString("# HELP " + f.name + " " + f.help + "\n")
+	w.WriteString("# TYPE " + f.name + " " + f.typ + "\n")
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := f.series[key]
+		if f.typ != "histogram" {
+			w.WriteString(f.name + "{" + s.labels + "} " + formatFloat(s.value) + "\n")
+			continue
+		}
+
+		for i, bound := range f.buckets {
+			w.WriteString(f.name + "_bucket{" + s.labels + `,le="` + formatFloat(bound) + `"} ` + strconv.FormatUint(s.counts[i], 10) + "\n")
+		}
+
+		w.WriteString(f.name + "_bucket{" + s.labels + `,le="+Inf"} ` + strconv.FormatUint(s.count, 10) + "\n")
+		w.WriteString(f.name + "_sum{" + s.labels + "} " + formatFloat(s.value) + "\n")
+		w.WriteString(f.name + "_count{" + s.labels + "} " + strconv.FormatUint(s.count, 10) + "\n")
+	}
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// escapeLabel escape label value as required by Prometheus text format
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// metricsHandler record metrics of requests to route method and path.
+// Metrics of Minirest are read on each request, so Minirest.Metrics can be called after AddController
+func (mn *Minirest) metricsHandler(method, path string, next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		m := mn.metrics
+		if m == nil {
+			next(w, r, p)
+			return
+		}
+
+		atomic.AddInt64(&m.inFlight, 1)
+		start := time.Now()
+		mw := &metricsWriter{statusWriter: statusWriter{ResponseWriter: w}}
+		body := &countingReader{ReadCloser: r.Body}
+		if r.ContentLength < 0 && r.Body != nil {
+			r.Body = body
+		}
+
+		defer func() {
+			atomic.AddInt64(&m.inFlight, -1)
+			size := r.ContentLength
+			if size < 0 {
+				size = body.n
+			}
+
+			// panic is recorded as 500 written by PanicHandler
+			rcv := recover()
+			if rcv != nil {
+				mw.status = http.StatusInternalServerError
+				mw.panicked = true
+			}
+
+			m.observe(method, path, mw, size, time.Since(start))
+			if rcv != nil {
+				panic(rcv)
+			}
+		}()
+
+		next(mw, r, p)
+	}
+}
+
+// metricsWriter record response of request, and events marked by handlers
+type metricsWriter struct {
+	statusWriter
+	bindFailed bool
+	panicked   bool
+	gzip       bool
+}
+
+// metricsWriterOf return metricsWriter wrapped by w, unwrapping response writers
+// of middlewares that implement Unwrap
+func metricsWriterOf(w http.ResponseWriter) *metricsWriter {
+	for {
+		switch v := w.(type) {
+		case *metricsWriter:
+			return v
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = v.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// markBindFailure count request that failed to bind, if metrics are enabled
+func markBindFailure(w http.ResponseWriter) {
+	if mw := metricsWriterOf(w); mw != nil {
+		mw.bindFailed = true
+	}
+}
+
+// markGzip count gzip encoded response, if metrics are enabled
+func markGzip(w http.ResponseWriter) {
+	if mw := metricsWriterOf(w); mw != nil {
+		mw.gzip = true
+	}
+}
+
+// countingReader count bytes read from request body of unknown length
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+	r.n += int64(n)
+
+	return n, err
+}
@@ -0,0 +1,85 @@
+package minirest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	silenceLog(t)
+	mn := newTestApp("GET", "/users/:id", func(id int) *ResponseBuilder {
+		if id == 0 {
+			panic("boom")
+		}
+
+		return echo(id)
+	})
+	mn.Gzip = true
+	mn.AddController(&testController{method: "POST", path: "/users", callback: func(body testBody) *ResponseBuilder { return echo(body) }})
+	mn.Metrics(MetricsOption{DurationBuckets: []float64{60}, SizeBuckets: []float64{10, 1000}})
+
+	serve(mn, "GET", "/users/1", nil)
+	serve(mn, "GET", "/users/2", nil)
+	serve(mn, "GET", "/users/x", nil)
+	serve(mn, "GET", "/users/0", nil)
+	serve(mn, "GET", "/not-found", nil)
+	serve(mn, "POST", "/users", strings.NewReader(`{"name":"John","age":20}`))
+
+	w := serve(mn, "GET", "/metrics", nil)
+	if got := w.Header().Get("Content-Type"); got != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	got := w.Body.String()
+	want := []string{
+		"# TYPE minirest_http_requests_in_flight gauge\nminirest_http_requests_in_flight 1\n",
+		"# TYPE minirest_http_requests_total counter\n",
+		`minirest_http_requests_total{method="GET",route="/users/:id",status="200"} 2`,
+		`minirest_http_requests_total{method="GET",route="/users/:id",status="400"} 1`,
+		`minirest_http_requests_total{method="GET",route="/users/:id",status="500"} 1`,
+		`minirest_http_requests_total{method="POST",route="/users",status="200"} 1`,
+		"# TYPE minirest_http_request_duration_seconds histogram\n",
+		`minirest_http_request_duration_seconds_bucket{method="GET",route="/users/:id",status="200",le="60"} 2`,
+		`minirest_http_request_duration_seconds_bucket{method="GET",route="/users/:id",status="200",le="+Inf"} 2`,
+		`minirest_http_request_duration_seconds_count{method="GET",route="/users/:id",status="200"} 2`,
+		`minirest_http_request_size_bytes_bucket{method="POST",route="/users",le="10"} 0`,
+		`minirest_http_request_size_bytes_bucket{method="POST",route="/users",le="1000"} 1`,
+		`minirest_http_request_size_bytes_sum{method="POST",route="/users"} 24`,
+		`minirest_http_response_size_bytes_count{method="GET",route="/users/:id",status="200"} 2`,
+		`minirest_bind_failures_total{method="GET",route="/users/:id"} 1`,
+		`minirest_panics_total{method="GET",route="/users/:id"} 1`,
+		`minirest_gzip_responses_total{method="POST",route="/users"} 1`,
+	}
+
+	for _, line := range want {
+		if !strings.Contains(got, line) {
+			t.Errorf("metrics doesn't contain %q", line)
+		}
+	}
+
+	if strings.Contains(got, "not-found") {
+		t.Error("unmatched request is counted")
+	}
+
+	if t.Failed() {
+		t.Log(got)
+	}
+}
+
+func TestMetricsTwice(t *testing.T) {
+	mn := New()
+	mn.Metrics(MetricsOption{})
+	defer func() {
+		if got := recover(); got != "minirest: Metrics already enabled" {
+			t.Errorf("panic = %v", got)
+		}
+	}()
+
+	mn.Metrics(MetricsOption{Path: "/other"})
+}
+
+func TestEscapeLabel(t *testing.T) {
+	if got := escapeLabel("a\\b\"c\nd"); got != `a\\b\"c\nd` {
+		t.Errorf("escapeLabel = %s", got)
+	}
+}
@@ -1,10 +1,15 @@
 package minirest
 
 import (
-	"log"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
 	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/julienschmidt/httprouter"
 )
@@ -12,12 +17,31 @@ import (
 // Minirest is singleton for Minirest framework
 type Minirest struct {
 	// Set to true for returning gzip encoded response globally
-	Gzip        bool
+	Gzip bool
+	// Set to true for printing route table when server starts
+	ShowRoutes bool
+	// Logger log internal messages, default is slog.Default()
+	Logger      Logger
 	services    map[string]Service
 	controllers map[string]Controller
 	router      *httprouter.Router
 	port        string
 	ip          string
+	server      *http.Server
+	mu          sync.Mutex
+	wsConns     map[*WebSocketConn]struct{}
+	shutdown    chan struct{}
+	envelope    Envelope
+	etagMode    ETagMode
+	cors        *CORSOption
+	corsPaths   map[string]*CORSOption
+	methods     map[string]bool
+	versioning  VersionOption
+	versions    map[string]*versionRoutes
+	routes      []RouteInfo
+	apiDoc      OpenAPIOption
+	accessLog   *accessLogger
+	metrics     *metrics
 }
 
 type keyVal struct {
@@ -27,14 +51,27 @@ type keyVal struct {
 
 // New initiate new Minirest
 func New() *Minirest {
-	return &Minirest{
+	mn := &Minirest{
 		services:    make(map[string]Service),
 		controllers: make(map[string]Controller),
 		router:      httprouter.New(),
+		wsConns:     make(map[*WebSocketConn]struct{}),
+		shutdown:    make(chan struct{}),
+		corsPaths:   make(map[string]*CORSOption),
+		methods:     make(map[string]bool),
+		versioning:  VersionOption{Header: defaultVersionHeader},
+		versions:    make(map[string]*versionRoutes),
 	}
+
+	mn.NotFoundHandler(nil)
+	mn.MethodNotAllowedHandler(nil)
+	mn.PanicHandler(nil)
+
+	return mn
 }
 
-// RunServer run http server
+// RunServer run http server.
+// RunServer returns after Shutdown is called
 func (mn *Minirest) RunServer() {
 	var addr string
 	if mn.ip != "" {
@@ -45,7 +82,30 @@ func (mn *Minirest) RunServer() {
 		addr += ":" + mn.port
 	}
 
-	log.Fatal(http.ListenAndServe(addr, mn.router))
+	// clientgen run the app with OpenAPIEnv set for reading its OpenAPI document
+	if os.Getenv(OpenAPIEnv) != "" {
+		json.NewEncoder(os.Stdout).Encode(mn.OpenAPIDocument(mn.apiDoc))
+		return
+	}
+
+	// minirest routes command run the app with RoutesEnv set for printing its routes
+	if os.Getenv(RoutesEnv) != "" {
+		mn.PrintRoutes(os.Stdout)
+		return
+	}
+
+	if mn.ShowRoutes {
+		mn.PrintRoutes(os.Stdout)
+	}
+
+	mn.mu.Lock()
+	mn.server = &http.Server{Addr: addr, Handler: mn}
+	mn.mu.Unlock()
+
+	if err := mn.server.ListenAndServe(); err != http.ErrServerClosed {
+		mn.log(context.Background(), slog.LevelError, "minirest: server stopped", "addr", addr, "error", err)
+		os.Exit(1)
+	}
 }
 
 // ServeIP set http server IP
@@ -58,6 +118,27 @@ func (mn *Minirest) ServePort(port string) {
 	mn.port = port
 }
 
+// ResponseEnvelope set Envelope for formatting responses built by ResponseBuilder helpers.
+// Default is DefaultEnvelope, which write Response as is
+func (mn *Minirest) ResponseEnvelope(env Envelope) {
+	mn.envelope = env
+}
+
+// Envelope return Envelope set by ResponseEnvelope, DefaultEnvelope if none is set
+func (mn *Minirest) Envelope() Envelope {
+	if mn.envelope == nil {
+		return DefaultEnvelope
+	}
+
+	return mn.envelope
+}
+
+// AutoETag set whether ETag is generated from the encoded body of 2xx responses.
+// Default is ETagOff, use ResponseBuilder.AutoETag to override it per response
+func (mn *Minirest) AutoETag(mode ETagMode) {
+	mn.etagMode = mode
+}
+
 // AddService add service.
 // Service must be pointer to struct
 func (mn *Minirest) AddService(service Service) {
@@ -70,6 +151,14 @@ func (mn *Minirest) AddService(service Service) {
 	}
 }
 
+// ReplaceService register fake as service, replacing service with the same type name as service.
+// Controllers and services added or linked after ReplaceService is called get fake instead.
+// fake is not initialized, and it must be assignable to fields of the replaced service
+func (mn *Minirest) ReplaceService(service, fake Service) {
+	servName := strings.Split(reflect.ValueOf(service).Type().String(), ".")
+	mn.services[servName[len(servName)-1]] = fake
+}
+
 // LinkService link service dest with services svc.
 // If service not registered, it will automatically registered.
 // Service must have fields with same name as services that want to be linked.
@@ -141,80 +230,146 @@ func (mn *Minirest) AddController(controller Controller, srv ...Service) {
 	}
 
 	// call controller.Endpoints and register all endpoints
-	endpoints := controller.Endpoints()
-	for _, endpoint := range endpoints.endpoints {
-		method := strings.ToLower(endpoint.method)
-		var handle httprouter.Handle
-		if method == "get" || method == "delete" {
-			if endpoints.middleware != nil {
-				handle = endpoints.middleware.handleChain(handleWithoutBody(endpoint.callback))
-			} else {
-				handle = handleWithoutBody(endpoint.callback)
-			}
-
-			if endpoints.Gzip || mn.Gzip {
-				handle = makeGzipHandler(handle)
-			}
+	ctrlName := strings.Split(val.Type().String(), ".")
+	mn.addEndpoints(controller.Endpoints(), routeScope{controller: ctrlName[len(ctrlName)-1]})
+	mn.controllers[ctrlName[len(ctrlName)-1]] = controller
+}
 
-			mn.router.Handle(endpoint.method, endpoints.basePath+endpoint.path, handle)
+// addEndpoints register endpoints of ep and its groups
+func (mn *Minirest) addEndpoints(ep *Endpoints, parent routeScope) {
+	scope := parent.inherit(ep)
+	for _, endpoint := range ep.endpoints {
+		if doc, ok := ep.docs[endpoint.method+" "+endpoint.path]; ok {
+			endpoint.doc = &doc
 		}
 
-		if method == "post" || method == "put" || method == "patch" {
-			if endpoints.middleware != nil {
-				handle = endpoints.middleware.handleChain(handleWithBody(endpoint.callback))
-			} else {
-				handle = handleWithBody(endpoint.callback)
-			}
+		mn.addEndpoint(scope, endpoint)
+	}
 
-			if endpoints.Gzip || mn.Gzip {
-				handle = makeGzipHandler(handle)
-			}
+	for _, group := range ep.groups {
+		mn.addEndpoints(group, scope)
+	}
+}
+
+func (mn *Minirest) addEndpoint(scope routeScope, endpoint endpoint) {
+	method := strings.ToLower(endpoint.method)
+	info := RouteInfo{
+		Method:     endpoint.method,
+		Path:       scope.basePath + endpoint.path,
+		Kind:       RouteREST,
+		Controller: scope.controller,
+		Handler:    funcName(endpoint.callback),
+		Version:    scope.version,
+		Doc:        endpoint.doc,
+	}
+
+	for _, md := range scope.middlewares {
+		info.Middlewares = append(info.Middlewares, funcName(md))
+	}
+
+	for _, md := range endpoint.middlewares {
+		info.Middlewares = append(info.Middlewares, funcName(md))
+	}
 
-			mn.router.Handle(endpoint.method, endpoints.basePath+endpoint.path, handle)
+	var handle httprouter.Handle
+	// SSE and WebSocket are streamed, so they can not be gzip encoded
+	if endpoint.kind == kindSSE || endpoint.kind == kindWebSocket {
+		if endpoint.kind == kindSSE {
+			info.Kind = RouteSSE
+			handle = mn.handleSSE(endpoint.callback.(func(*EventStream)), scope.sseHeartbeat)
+		} else {
+			info.Kind = RouteWebSocket
+			handle = mn.handleWebSocket(endpoint.callback.(func(*WebSocketConn)), scope.wsOption)
 		}
+
+		mn.handle(scope, info, scope.chain(handle, endpoint.middlewares))
+		return
 	}
 
-	ctrlName := strings.Split(val.Type().String(), ".")
-	mn.controllers[ctrlName[len(ctrlName)-1]] = controller
-}
+	if endpoint.kind == kindTyped {
+		handle = mn.addTyped(endpoint, &info)
+	}
 
-// CORS set CORS
-func (mn *Minirest) CORS(opt CORSOption) {
-	mn.router.HandleMethodNotAllowed = true
-	mn.router.HandleOPTIONS = true
-	mn.router.GlobalOPTIONS = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Header.Get("Access-Control-Request-Method") != "" {
-			header := w.Header()
-			if opt.AllowOrigin != "" {
-				header.Add("Access-Control-Allow-Origin", opt.AllowOrigin)
-			}
+	if endpoint.kind == kindREST && (method == "get" || method == "head" || method == "delete") {
+		plan := newBindingPlan(endpoint.callback, info.Path, false)
+		info.Bindings = plan.bindings(info.Path)
+		handle = mn.handleWithoutBody(plan)
+	}
 
-			if opt.AllowCredentials != "" {
-				header.Add("Access-Control-Allow-Credentials", opt.AllowCredentials)
-			}
+	if endpoint.kind == kindREST && (method == "post" || method == "put" || method == "patch") {
+		plan := newBindingPlan(endpoint.callback, info.Path, true)
+		info.Bindings = plan.bindings(info.Path)
+		handle = mn.handleWithBody(plan)
+	}
 
-			if opt.ExposeHeaders != "" {
-				header.Add("Access-Control-Expose-Headers", opt.ExposeHeaders)
-			}
+	if handle == nil {
+		return
+	}
 
-			if opt.AllowHeaders != "" {
-				header.Add("Access-Control-Allow-Headers", opt.AllowHeaders)
-			}
+	// If-Match is checked after middlewares, right before the callback is called
+	if scope.ifMatch != nil && (method == "put" || method == "patch" || method == "delete") {
+		handle = mn.ifMatchHandler(scope.ifMatch, handle)
+	}
 
-			if opt.AllowMethods != "" {
-				header.Add("Access-Control-Allow-Methods", opt.AllowMethods)
-			}
+	if scope.cache != nil && (method == "get" || method == "head") {
+		handle = cacheHandler(scope.cache, scope.cacheOption, scope.version, handle)
+	}
+
+	handle = scope.chain(handle, endpoint.middlewares)
+	if scope.gzip || mn.Gzip {
+		handle = makeGzipHandler(handle)
+	}
+
+	mn.handle(scope, info, handle)
+}
+
+// handle register handle of endpoint described by info into router
+func (mn *Minirest) handle(scope routeScope, info RouteInfo, handle httprouter.Handle) {
+	handle = mn.corsHandler(scope.cors, handle)
+	if scope.version != "" {
+		mn.handleVersion(scope, info, handle)
+		return
+	}
+
+	mn.register(scope, info.Method, info.Path, handle)
+	mn.addRoute(info, info.Path)
+}
+
+// register register handle into router with path as is.
+// Conflicting routes are reported with the registered routes they may conflict with
+func (mn *Minirest) register(scope routeScope, method, path string, handle httprouter.Handle) {
+	defer func() {
+		if rcv := recover(); rcv != nil {
+			panic(fmt.Sprintf("minirest: can not register %s %s: %v%s", method, path, rcv, mn.conflicts(method, path)))
 		}
+	}()
+
+	handle = mn.metricsHandler(method, path, handle)
+	mn.router.Handle(method, path, mn.accessLogHandler(scope, method, path, handle))
+	mn.methods[method] = true
+	// preflight is answered per path, so all endpoints of path must have the same options
+	if scope.cors == nil {
+		return
+	}
 
-		w.WriteHeader(http.StatusNoContent)
-	})
+	if opt, ok := mn.corsPaths[path]; ok {
+		if !opt.equal(scope.cors) {
+			panic("CORS options conflict with other endpoints of the same path")
+		}
+
+		return
+	}
+
+	mn.corsPaths[path] = scope.cors
+	mn.router.Handle(http.MethodOptions, path, mn.preflightHandler(scope.cors))
 }
 
-// CORSOption set options for CORS headers
-type CORSOption struct {
-	AllowOrigin      string
-	AllowCredentials string
-	ExposeHeaders    string
-	AllowHeaders     string
-	AllowMethods     string
+func (mn *Minirest) writeResponse(w http.ResponseWriter, r *http.Request, resp *ResponseBuilder) {
+	if resp.etagMode == ETagInherit {
+		resp.etagMode = mn.etagMode
+	}
+
+	if err := resp.write(w, r, mn.envelope); err != nil {
+		mn.logRequest(r, slog.LevelError, "minirest: write response", "error", err)
+	}
 }
@@ -0,0 +1,167 @@
+// Package minitest send in-memory requests to minirest app and assert its responses.
+//
+// Example:
+//
+//	func TestGetUser(t *testing.T) {
+//		app := minitest.New(t)
+//		app.Fake(new(UserService), &UserService{Users: map[int]User{1: {Name: "John"}}})
+//		app.Controller(new(UserController), new(UserService))
+//
+//		app.GET("/users/1").Do().
+//			Status(http.StatusOK).
+//			JSON("body.name", "John")
+//	}
+package minitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/tamboto2000/minirest"
+)
+
+// App is minirest app under test
+type App struct {
+	t  testing.TB
+	mn *minirest.Minirest
+}
+
+// New create App with new Minirest
+func New(t testing.TB) *App {
+	return &App{t: t, mn: minirest.New()}
+}
+
+// Wrap create App from configured Minirest, such as the one built by main package
+func Wrap(t testing.TB, mn *minirest.Minirest) *App {
+	return &App{t: t, mn: mn}
+}
+
+// Minirest return the underlying Minirest for further configuration
+func (app *App) Minirest() *minirest.Minirest {
+	return app.mn
+}
+
+// Fake replace service with fake, see Minirest.ReplaceService.
+// Fake must be called before adding controllers and services that link service
+func (app *App) Fake(service, fake minirest.Service) *App {
+	app.mn.ReplaceService(service, fake)
+	return app
+}
+
+// Service add service, see Minirest.AddService
+func (app *App) Service(service minirest.Service) *App {
+	app.mn.AddService(service)
+	return app
+}
+
+// Controller add controller and link services srv into it, see Minirest.AddController
+func (app *App) Controller(controller minirest.Controller, srv ...minirest.Service) *App {
+	app.mn.AddController(controller, srv...)
+	return app
+}
+
+// Request create request with method and path, path can contain query
+func (app *App) Request(method, path string) *Request {
+	return &Request{app: app, method: method, path: path, header: make(http.Header), query: make(url.Values)}
+}
+
+// GET create request with method GET
+func (app *App) GET(path string) *Request {
+	return app.Request(http.MethodGet, path)
+}
+
+// HEAD create request with method HEAD
+func (app *App) HEAD(path string) *Request {
+	return app.Request(http.MethodHead, path)
+}
+
+// DELETE create request with method DELETE
+func (app *App) DELETE(path string) *Request {
+	return app.Request(http.MethodDelete, path)
+}
+
+// POST create request with method POST and JSON body
+func (app *App) POST(path string, body interface{}) *Request {
+	return app.Request(http.MethodPost, path).JSON(body)
+}
+
+// PUT create request with method PUT and JSON body
+func (app *App) PUT(path string, body interface{}) *Request {
+	return app.Request(http.MethodPut, path).JSON(body)
+}
+
+// PATCH create request with method PATCH and JSON body
+func (app *App) PATCH(path string, body interface{}) *Request {
+	return app.Request(http.MethodPatch, path).JSON(body)
+}
+
+// Request is request sent in-memory to App
+type Request struct {
+	app    *App
+	method string
+	path   string
+	header http.Header
+	query  url.Values
+	body   io.Reader
+}
+
+// Header set request header
+func (req *Request) Header(key, value string) *Request {
+	req.header.Set(key, value)
+	return req
+}
+
+// Query add query parameter
+func (req *Request) Query(key, value string) *Request {
+	req.query.Add(key, value)
+	return req
+}
+
+// JSON set body encoded as JSON, and Content-Type to application/json
+func (req *Request) JSON(body interface{}) *Request {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		req.app.t.Helper()
+		req.app.t.Fatalf("minitest: encode body of %s %s: %v", req.method, req.path, err)
+	}
+
+	req.header.Set("Content-Type", "application/json")
+	req.body = bytes.NewReader(raw)
+	return req
+}
+
+// Body set raw body
+func (req *Request) Body(body []byte) *Request {
+	req.body = bytes.NewReader(body)
+	return req
+}
+
+// Do send request and return its response
+func (req *Request) Do() *Response {
+	req.app.t.Helper()
+	target := req.path
+	if len(req.query) > 0 {
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+
+		target += sep + req.query.Encode()
+	}
+
+	r := httptest.NewRequest(req.method, target, req.body)
+	for key, values := range req.header {
+		r.Header[key] = values
+	}
+
+	w := httptest.NewRecorder()
+	req.app.mn.ServeHTTP(w, r)
+
+	return newResponse(req.app.t, req.method+" "+target, w.Result(), req.app.mn.Envelope())
+}
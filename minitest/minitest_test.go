@@ -0,0 +1,295 @@
+package minitest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/tamboto2000/minirest"
+)
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserService struct {
+	Users map[int]user
+}
+
+func (svc *UserService) Init() {
+	svc.Users = map[int]user{1: {ID: 1, Name: "John"}}
+}
+
+type UserController struct {
+	UserService *UserService
+}
+
+type userFilter struct {
+	Name string `schema:"name"`
+}
+
+func (ctrl *UserController) List(filter userFilter) *minirest.ResponseBuilder {
+	var users []user
+	for _, u := range ctrl.UserService.Users {
+		if filter.Name == "" || u.Name == filter.Name {
+			users = append(users, u)
+		}
+	}
+
+	return new(minirest.ResponseBuilder).Ok(users)
+}
+
+func (ctrl *UserController) Get(id int) *minirest.ResponseBuilder {
+	u, ok := ctrl.UserService.Users[id]
+	if !ok {
+		return new(minirest.ResponseBuilder).NotFound(fmt.Sprintf("user %d not found", id))
+	}
+
+	return new(minirest.ResponseBuilder).SetHeader("X-User", u.Name).Ok(u)
+}
+
+func (ctrl *UserController) Create(u user) *minirest.ResponseBuilder {
+	if u.Name == "" {
+		return new(minirest.ResponseBuilder).UnprocessableEntity("invalid user", map[string]string{"name": "required"})
+	}
+
+	return new(minirest.ResponseBuilder).Created("/users/2", u)
+}
+
+func (ctrl *UserController) Delete(id int) *minirest.ResponseBuilder {
+	return new(minirest.ResponseBuilder).NoContent("")
+}
+
+func (ctrl *UserController) Endpoints() *minirest.Endpoints {
+	ep := new(minirest.Endpoints)
+	ep.BasePath("/users")
+	ep.GET("", ctrl.List)
+	ep.GET("/:id", ctrl.Get)
+	ep.POST("", ctrl.Create)
+	ep.DELETE("/:id", ctrl.Delete)
+
+	return ep
+}
+
+// fakeT record failures reported by assertions, instead of failing the test
+type fakeT struct {
+	testing.TB
+	errors []string
+	fatal  string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeT) Fatalf(format string, args ...interface{}) {
+	t.fatal = fmt.Sprintf(format, args...)
+	// stop the assertion like testing.T do, recovered by run
+	panic(t)
+}
+
+// run call fn and recover from Fatalf
+func (t *fakeT) run(fn func()) {
+	defer func() {
+		if rcv := recover(); rcv != nil && rcv != t {
+			panic(rcv)
+		}
+	}()
+
+	fn()
+}
+
+func newApp(t testing.TB) *App {
+	return New(t).Controller(new(UserController), new(UserService))
+}
+
+func TestAssertions(t *testing.T) {
+	app := newApp(t)
+	app.GET("/users/1").Do().
+		Status(http.StatusOK).
+		Header("Content-Type", "application/json").
+		Header("X-User", "John").
+		NoHeader("Location").
+		JSON("statusCode", 200).
+		JSON("body", user{ID: 1, Name: "John"}).
+		JSON("body.name", "John").
+		JSONExists("status")
+
+	app.GET("/users").Query("name", "John").Do().
+		Status(http.StatusOK).
+		JSON("body.0.id", 1)
+
+	app.POST("/users", user{ID: 2, Name: "Jane"}).Do().
+		Status(http.StatusCreated).
+		Header("Location", "/users/2")
+
+	app.Request(http.MethodPost, "/users").Header("Content-Type", "application/json").Body([]byte(`{"name":"Raw"}`)).Do().
+		Status(http.StatusCreated).
+		JSON("body.name", "Raw")
+
+	app.DELETE("/users/1").Do().
+		Status(http.StatusNoContent).
+		BodyEquals("")
+
+	var got user
+	app.GET("/users/1").Do().Decode(&got)
+	if got != (user{ID: 1, Name: "John"}) {
+		t.Errorf("Decode = %+v", got)
+	}
+
+	env := app.GET("/users/9").Do().Status(http.StatusNotFound).Envelope()
+	if env.StatusCode != 404 || env.Status != "not_found" || env.Description != "user 9 not found" || env.Body != nil {
+		t.Errorf("Envelope = %+v", env)
+	}
+}
+
+func TestFake(t *testing.T) {
+	app := New(t).
+		Fake(new(UserService), &UserService{Users: map[int]user{5: {ID: 5, Name: "Fake"}}}).
+		Controller(new(UserController), new(UserService))
+
+	app.GET("/users/5").Do().Status(http.StatusOK).JSON("body.name", "Fake")
+	app.GET("/users/1").Do().Status(http.StatusNotFound)
+}
+
+func TestGzip(t *testing.T) {
+	mn := minirest.New()
+	mn.Gzip = true
+	app := Wrap(t, mn).Controller(new(UserController), new(UserService))
+	app.GET("/users/1").Header("Accept-Encoding", "gzip").Do().
+		Header("Content-Encoding", "gzip").
+		JSON("body.name", "John")
+}
+
+func TestEnvelopes(t *testing.T) {
+	tests := []struct {
+		name string
+		env  minirest.Envelope
+	}{
+		{name: "default", env: minirest.DefaultEnvelope},
+		{name: "raw", env: minirest.RawEnvelope},
+		{name: "jsonapi", env: minirest.JSONAPIEnvelope},
+		{name: "problem", env: minirest.ProblemEnvelope(minirest.RawEnvelope)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newApp(t)
+			app.Minirest().ResponseEnvelope(tt.env)
+
+			var got user
+			app.GET("/users/1").Do().Status(http.StatusOK).Decode(&got)
+			if got != (user{ID: 1, Name: "John"}) {
+				t.Errorf("Decode = %+v", got)
+			}
+
+			env := app.GET("/users/9").Do().Status(http.StatusNotFound).Envelope()
+			if env.StatusCode != 404 || env.Status != "not_found" || env.Description != "user 9 not found" {
+				t.Errorf("Envelope = %+v", env)
+			}
+
+			var details map[string]string
+			app.POST("/users", user{}).Do().Status(http.StatusUnprocessableEntity).Decode(&details)
+			if details["name"] != "required" {
+				t.Errorf("details = %v", details)
+			}
+
+			if env := app.DELETE("/users/1").Do().Envelope(); env.StatusCode != 204 || env.Body != nil {
+				t.Errorf("Envelope of response without body = %+v", env)
+			}
+		})
+	}
+}
+
+func TestFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		assert func(app *App)
+		errors []string
+		fatal  string
+	}{
+		{
+			name:   "status",
+			assert: func(app *App) { app.GET("/users/9").Do().Status(http.StatusOK) },
+			errors: []string{`GET /users/9: status = 404, want 200` + "\nbody: " + `{"statusCode":404,"status":"not_found","description":"user 9 not found"}`},
+		},
+		{
+			name:   "header",
+			assert: func(app *App) { app.GET("/users/1").Do().Header("X-User", "Jane").NoHeader("X-User") },
+			errors: []string{`GET /users/1: header X-User = "John", want "Jane"`, `GET /users/1: header X-User = ["John"], want not set`},
+		},
+		{
+			name: "json value",
+			assert: func(app *App) {
+				app.GET("/users/1").Do().JSON("body.name", "Jane").JSON("body", map[string]int{"id": 1})
+			},
+			errors: []string{`GET /users/1: body.name = "John", want "Jane"`, `GET /users/1: body = {"id":1,"name":"John"}, want {"id":1}`},
+		},
+		{
+			name: "json path",
+			assert: func(app *App) {
+				app.GET("/users?name=John").Do().JSON("body.3.id", 1).JSONExists("body.0.age").JSON("status.x", 1)
+			},
+			errors: []string{
+				"GET /users?name=John: body.3 not found, array has 1 elements",
+				"GET /users?name=John: body.0.age not found",
+				"GET /users?name=John: status.x not found, parent is not object or array",
+			},
+		},
+		{
+			name:   "body",
+			assert: func(app *App) { app.DELETE("/users/1").Do().BodyEquals("ok").BodyEquals([]byte("ok")).JSON("", nil) },
+			errors: []string{`DELETE /users/1: body = "", want "ok"`, `DELETE /users/1: body = "", want "ok"`, "DELETE /users/1: body is not JSON"},
+		},
+		{
+			name:   "decode without data",
+			assert: func(app *App) { app.GET("/users/9").Do().Decode(new(user)) },
+			fatal:  "minitest: GET /users/9: decode body: response has no data",
+		},
+		{
+			name:   "decode into wrong type",
+			assert: func(app *App) { app.GET("/users/1").Do().Decode(new(string)) },
+			fatal:  "minitest: GET /users/1: decode body: json: cannot unmarshal",
+		},
+		{
+			name:   "encode request body",
+			assert: func(app *App) { app.POST("/users", make(chan int)) },
+			fatal:  "minitest: encode body of POST /users: json: unsupported type: chan int",
+		},
+		{
+			name: "custom envelope",
+			assert: func(app *App) {
+				app.Minirest().ResponseEnvelope(minirest.EnvelopeFunc(func(r *http.Request, resp minirest.Response) (string, interface{}) {
+					return "application/json", resp.Body
+				}))
+
+				app.GET("/users/1").Do().Envelope()
+			},
+			fatal: "minitest: GET /users/1: decode envelope: minirest: envelope minirest.EnvelopeFunc doesn't implement EnvelopeDecoder",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ft := &fakeT{TB: t}
+			ft.run(func() { tt.assert(newApp(ft)) })
+			if len(ft.errors) != len(tt.errors) {
+				t.Fatalf("errors = %q, want %q", ft.errors, tt.errors)
+			}
+
+			for i, want := range tt.errors {
+				if !strings.HasPrefix(ft.errors[i], want) {
+					t.Errorf("error %d = %q, want %q", i, ft.errors[i], want)
+				}
+			}
+
+			if !strings.HasPrefix(ft.fatal, tt.fatal) || (tt.fatal == "") != (ft.fatal == "") {
+				t.Errorf("fatal = %q, want %q", ft.fatal, tt.fatal)
+			}
+		})
+	}
+}
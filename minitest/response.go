@@ -0,0 +1,238 @@
+package minitest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/tamboto2000/minirest"
+)
+
+// Response is response of Request.
+// Assertions report failures with t.Errorf and return the response, so they can be chained
+type Response struct {
+	t    testing.TB
+	name string
+	resp *http.Response
+	body []byte
+	env  minirest.Envelope
+}
+
+func newResponse(t testing.TB, name string, resp *http.Response, env minirest.Envelope) *Response {
+	t.Helper()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("minitest: read response of %s: %v", name, err)
+	}
+
+	// gzip encoded body is decoded, so assertions work the same with Gzip enabled
+	if resp.Header.Get("Content-Encoding") == "gzip" && len(body) > 0 {
+		gr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("minitest: decode gzip response of %s: %v", name, err)
+		}
+
+		if body, err = io.ReadAll(gr); err != nil {
+			t.Fatalf("minitest: decode gzip response of %s: %v", name, err)
+		}
+	}
+
+	return &Response{t: t, name: name, resp: resp, body: body, env: env}
+}
+
+// HTTP return the underlying response, its body is already read
+func (resp *Response) HTTP() *http.Response {
+	return resp.resp
+}
+
+// Raw return response body, decoded if it's gzip encoded
+func (resp *Response) Raw() []byte {
+	return resp.body
+}
+
+// Envelope decode response body into Response with envelope of the app, see minirest.DecodeResponse.
+// Body of the returned Response is decoded as interface{}
+func (resp *Response) Envelope() minirest.Response {
+	resp.t.Helper()
+	env := resp.decodeEnvelope()
+	if raw, ok := env.Body.(json.RawMessage); ok {
+		var body interface{}
+		json.Unmarshal(raw, &body)
+		env.Body = body
+	}
+
+	return env
+}
+
+// Decode decode data of the response, unwrapped with envelope of the app, into v
+func (resp *Response) Decode(v interface{}) *Response {
+	resp.t.Helper()
+	raw, ok := resp.decodeEnvelope().Body.(json.RawMessage)
+	if !ok {
+		resp.t.Fatalf("minitest: %s: decode body: response has no data\nbody: %s", resp.name, resp.body)
+	}
+
+	if err := json.Unmarshal(raw, v); err != nil {
+		resp.t.Fatalf("minitest: %s: decode body: %v\nbody: %s", resp.name, err, raw)
+	}
+
+	return resp
+}
+
+func (resp *Response) decodeEnvelope() minirest.Response {
+	resp.t.Helper()
+	env, err := minirest.DecodeResponse(resp.env, resp.resp.StatusCode, resp.body)
+	if err != nil {
+		resp.t.Fatalf("minitest: %s: decode envelope: %v\nbody: %s", resp.name, err, resp.body)
+	}
+
+	return env
+}
+
+// Status assert status code
+func (resp *Response) Status(code int) *Response {
+	resp.t.Helper()
+	if resp.resp.StatusCode != code {
+		resp.t.Errorf("%s: status = %d, want %d\nbody: %s", resp.name, resp.resp.StatusCode, code, resp.body)
+	}
+
+	return resp
+}
+
+// Header assert value of response header
+func (resp *Response) Header(key, value string) *Response {
+	resp.t.Helper()
+	if got := resp.resp.Header.Get(key); got != value {
+		resp.t.Errorf("%s: header %s = %q, want %q", resp.name, key, got, value)
+	}
+
+	return resp
+}
+
+// NoHeader assert response header is not set
+func (resp *Response) NoHeader(key string) *Response {
+	resp.t.Helper()
+	if got, ok := resp.resp.Header[http.CanonicalHeaderKey(key)]; ok {
+		resp.t.Errorf("%s: header %s = %q, want not set", resp.name, key, got)
+	}
+
+	return resp
+}
+
+// JSON assert value at path of JSON body equals want, compared after encoding want to JSON.
+// path is dot separated keys and array indexes of the body as written, including the envelope,
+// such as "body.items.0.name" with DefaultEnvelope or "data.items.0.name" with JSONAPIEnvelope,
+// empty path is the whole body
+func (resp *Response) JSON(path string, want interface{}) *Response {
+	resp.t.Helper()
+	got, err := lookup(resp.body, path)
+	if err != nil {
+		resp.t.Errorf("%s: %v\nbody: %s", resp.name, err, resp.body)
+		return resp
+	}
+
+	if !jsonEqual(got, want) {
+		raw, _ := json.Marshal(got)
+		wantRaw, _ := json.Marshal(want)
+		resp.t.Errorf("%s: %s = %s, want %s", resp.name, displayPath(path), raw, wantRaw)
+	}
+
+	return resp
+}
+
+// JSONExists assert path exists in JSON body
+func (resp *Response) JSONExists(path string) *Response {
+	resp.t.Helper()
+	if _, err := lookup(resp.body, path); err != nil {
+		resp.t.Errorf("%s: %v\nbody: %s", resp.name, err, resp.body)
+	}
+
+	return resp
+}
+
+// BodyEquals assert body equals want.
+// string and []byte are compared as is, other types are compared as JSON
+func (resp *Response) BodyEquals(want interface{}) *Response {
+	resp.t.Helper()
+	switch want := want.(type) {
+	case string:
+		if string(resp.body) != want {
+			resp.t.Errorf("%s: body = %q, want %q", resp.name, resp.body, want)
+		}
+	case []byte:
+		if !bytes.Equal(resp.body, want) {
+			resp.t.Errorf("%s: body = %q, want %q", resp.name, resp.body, want)
+		}
+	default:
+		resp.JSON("", want)
+	}
+
+	return resp
+}
+
+// lookup return value at path of JSON document raw
+func lookup(raw []byte, path string) (interface{}, error) {
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("body is not JSON: %v", err)
+	}
+
+	if path == "" {
+		return doc, nil
+	}
+
+	current := doc
+	for i, key := range strings.Split(path, ".") {
+		walked := strings.Join(strings.Split(path, ".")[:i+1], ".")
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[key]
+			if !ok {
+				return nil, fmt.Errorf("%s not found", walked)
+			}
+
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, fmt.Errorf("%s not found, array has %d elements", walked, len(node))
+			}
+
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("%s not found, parent is not object or array", walked)
+		}
+	}
+
+	return current, nil
+}
+
+// jsonEqual compare decoded JSON value got with want encoded to JSON
+func jsonEqual(got, want interface{}) bool {
+	raw, err := json.Marshal(want)
+	if err != nil {
+		return false
+	}
+
+	var normalized interface{}
+	if err := json.Unmarshal(raw, &normalized); err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(got, normalized)
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "body"
+	}
+
+	return path
+}
@@ -0,0 +1,92 @@
+package minirest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// ServeHTTP serve request with Minirest router,
+// so Minirest can be used as http.Handler of any http.Server
+func (mn *Minirest) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mn.router.ServeHTTP(w, r)
+}
+
+// Handler register plain http.Handler for method and path, such as metrics endpoint.
+// Path variables can be read with httprouter.ParamsFromContext.
+// CORS options set by Minirest.CORS apply to handler as to other endpoints
+func (mn *Minirest) Handler(method, path string, handler http.Handler) {
+	mn.register(routeScope{}, method, path, mn.corsHandler(nil, func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		if len(p) > 0 {
+			r = r.WithContext(context.WithValue(r.Context(), httprouter.ParamsKey, p))
+		}
+
+		handler.ServeHTTP(w, r)
+	}))
+
+	mn.addRoute(RouteInfo{Method: method, Kind: RouteHandler}, path)
+}
+
+// Mount mount handler under prefix for all methods, such as static files or another Minirest.
+// prefix is stripped from request path before the request is passed to handler,
+// so handler see request to prefix+"/users" as request to "/users".
+// Handler mounted at "/" receive all requests as is, and conflicts with any other route.
+// CORS options set by Minirest.CORS apply to mounted handler, and its preflight requests
+// are answered by Minirest
+func (mn *Minirest) Mount(prefix string, handler http.Handler) {
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix = "/" + prefix
+	}
+
+	handle := mn.corsHandler(nil, mountHandle(prefix, handler))
+	options := mn.mountOptions(handle)
+	// methods is built locally, so methodOrder can't be modified through spare capacity
+	methods := make([]string, 0, len(methodOrder)+1)
+	methods = append(append(methods, methodOrder...), http.MethodOptions)
+	for _, method := range methods {
+		handle := handle
+		if method == http.MethodOptions {
+			handle = options
+		}
+
+		// "/*mountpath" already match the root path
+		if prefix != "" {
+			mn.register(routeScope{}, method, prefix, handle)
+		}
+
+		mn.register(routeScope{}, method, prefix+"/*mountpath", handle)
+		mn.addRoute(RouteInfo{Method: method, Kind: RouteMount}, prefix+"/*mountpath")
+	}
+}
+
+// mountOptions answer preflight request of mounted path when Minirest.CORS is set,
+// as it is registered for OPTIONS and not answered by router. Other OPTIONS requests are passed to next
+func (mn *Minirest) mountOptions(next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		if mn.cors != nil && r.Header.Get("Origin") != "" && r.Header.Get("Access-Control-Request-Method") != "" {
+			mn.preflightHandler(mn.cors)(w, r, p)
+			return
+		}
+
+		next(w, r, p)
+	}
+}
+
+func mountHandle(prefix string, handler http.Handler) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		r2 := new(http.Request)
+		*r2 = *r
+		u := *r.URL
+		u.Path = strings.TrimPrefix(r.URL.Path, prefix)
+		if u.Path == "" {
+			u.Path = "/"
+		}
+
+		u.RawPath = ""
+		r2.URL = &u
+		handler.ServeHTTP(w, r2)
+	}
+}
@@ -0,0 +1,109 @@
+package minirest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestMount(t *testing.T) {
+	var got *http.Request
+	mn := New()
+	mn.Mount("static/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		method string
+		target string
+		path   string
+		query  string
+	}{
+		{method: "GET", target: "/static/css/app.css", path: "/css/app.css"},
+		{method: "GET", target: "/static", path: "/"},
+		{method: "GET", target: "/static/", path: "/"},
+		{method: "POST", target: "/static/upload?name=a", path: "/upload", query: "name=a"},
+		{method: "OPTIONS", target: "/static/a%20b", path: "/a b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			got = nil
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+			w := httptest.NewRecorder()
+			mn.ServeHTTP(w, r)
+			if w.Code != http.StatusNoContent || got == nil {
+				t.Fatalf("status = %d, handler called %v", w.Code, got != nil)
+			}
+
+			if got.URL.Path != tt.path || got.URL.RawQuery != tt.query {
+				t.Errorf("path = %q, query %q, want %q, %q", got.URL.Path, got.URL.RawQuery, tt.path, tt.query)
+			}
+
+			// request of the caller is not modified
+			if r.URL.Path == got.URL.Path {
+				t.Errorf("request path of caller is modified to %q", r.URL.Path)
+			}
+		})
+	}
+
+	if w := serve(mn, "GET", "/other", nil); w.Code != 404 {
+		t.Errorf("status outside prefix = %d", w.Code)
+	}
+}
+
+func TestMountRoot(t *testing.T) {
+	var got string
+	mn := New()
+	mn.Mount("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Path
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for _, target := range []string{"/", "/users/1", "/static/css/app.css"} {
+		got = ""
+		if w := serve(mn, "GET", target, nil); w.Code != http.StatusNoContent || got != target {
+			t.Errorf("%s: status = %d, path %q", target, w.Code, got)
+		}
+	}
+}
+
+func TestMountApp(t *testing.T) {
+	sub := newTestApp("GET", "/users/:id", func(id int) *ResponseBuilder { return echo(id) })
+	mn := newTestApp("GET", "/health", func() *ResponseBuilder { return echo("ok") })
+	mn.Mount("/v2", sub)
+
+	w := serve(mn, "GET", "/v2/users/5", nil)
+	if w.Code != 200 || decodeBody(t, w) != float64(5) {
+		t.Errorf("status = %d, body %s", w.Code, w.Body)
+	}
+
+	// not found of mounted app is written by the mounted app, with stripped path
+	w = serve(mn, "GET", "/v2/missing", nil)
+	if w.Code != 404 || w.Body.String() != `{"statusCode":404,"status":"not_found","description":"path /missing not found"}`+"\n" {
+		t.Errorf("status = %d, body %s", w.Code, w.Body)
+	}
+
+	if w := serve(mn, "GET", "/health", nil); w.Code != 200 {
+		t.Errorf("status of parent route = %d", w.Code)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	mn := New()
+	mn.Handler("GET", "/files/:name", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(httprouter.ParamsFromContext(r.Context()).ByName("name")))
+	}))
+
+	w := serve(mn, "GET", "/files/a.txt", nil)
+	if w.Code != 200 || w.Body.String() != "a.txt" {
+		t.Errorf("status = %d, body %q", w.Code, w.Body)
+	}
+
+	if w := serve(mn, "POST", "/files/a.txt", nil); w.Code != 405 {
+		t.Errorf("status of other method = %d", w.Code)
+	}
+}
@@ -0,0 +1,677 @@
+package minirest
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/tamboto2000/minirest/openapi"
+)
+
+// default paths for OpenAPI document and its docs page
+const (
+	defaultOpenAPIPath = "/openapi.json"
+	defaultDocsPath    = "/docs"
+)
+
+// OpenAPIEnv is environment variable that make RunServer write OpenAPI document
+// of the app to stdout and return, instead of serving it
+const OpenAPIEnv = "MINIREST_OPENAPI"
+
+// OpenAPIOption is options for generating and serving OpenAPI document.
+// Minirest doesn't bundle Swagger UI nor Redoc, their bundles are larger than minirest itself
+// and would be compiled into every app. The built-in docs page is a minimal offline viewer
+// listing operations, parameters, responses and schemas. For Redoc, vendor its bundle and set RedocScript
+type OpenAPIOption struct {
+	Title       string
+	Version     string
+	Description string
+	// Servers is URLs of servers serving the API
+	Servers []string
+	// Path is path serving the document, default is /openapi.json
+	Path string
+	// DocsPath is path serving the docs page, default is /docs
+	DocsPath string
+	// Set to true for not serving the docs page
+	NoDocs bool
+	// RedocScript is Redoc standalone bundle (redoc.standalone.js), usually embedded with go:embed.
+	// If set, the bundle is served next to the docs page and the page render the document with Redoc
+	// instead of the built-in viewer, without loading anything from CDN
+	RedocScript []byte
+}
+
+// OpenAPI serve OpenAPI 3.1 document of all registered routes at opt.Path,
+// and a docs page rendering it at opt.DocsPath.
+// The document is generated on first request, so routes added after OpenAPI is called are included.
+// The docs page is a minimal viewer embedded in minirest, or Redoc if opt.RedocScript is set,
+// both work offline, see OpenAPIOption
+func (mn *Minirest) OpenAPI(opt OpenAPIOption) {
+	if opt.Path == "" {
+		opt.Path = defaultOpenAPIPath
+	}
+
+	if opt.DocsPath == "" {
+		opt.DocsPath = defaultDocsPath
+	}
+
+	mn.apiDoc = opt
+	var once sync.Once
+	var doc []byte
+	mn.Handler(http.MethodGet, opt.Path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() {
+			doc, _ = json.MarshalIndent(mn.OpenAPIDocument(opt), "", "  ")
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(doc)
+	}))
+
+	if opt.NoDocs {
+		return
+	}
+
+	page := strings.Replace(docsPage, "{{spec}}", strconv.Quote(opt.Path), -1)
+	if opt.RedocScript != nil {
+		script := opt.RedocScript
+		page = strings.Replace(redocPage, "{{spec}}", htmlEscape(opt.Path), -1)
+		page = strings.Replace(page, "{{script}}", htmlEscape(redocScriptPath(opt.DocsPath)), -1)
+		mn.Handler(http.MethodGet, redocScriptPath(opt.DocsPath), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+			w.Write(script)
+		}))
+	}
+
+	page = strings.Replace(page, "{{title}}", htmlEscape(opt.Title), -1)
+	mn.Handler(http.MethodGet, opt.DocsPath, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	}))
+}
+
+// redocScriptPath return path serving Redoc bundle of docs page at docsPath
+func redocScriptPath(docsPath string) string {
+	return strings.TrimSuffix(docsPath, "/") + "/redoc.standalone.js"
+}
+
+// OpenAPIDocument generate OpenAPI 3.1 document of all registered routes.
+// Schemas are generated from callback parameters and EndpointDoc.Response,
+// responses are described by the shape written by the configured envelope.
+// Mounted handlers and the document itself are not included
+func (mn *Minirest) OpenAPIDocument(opt OpenAPIOption) *openapi.Document {
+	if opt.Path == "" {
+		opt.Path = defaultOpenAPIPath
+	}
+
+	if opt.DocsPath == "" {
+		opt.DocsPath = defaultDocsPath
+	}
+
+	doc := &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info: openapi.Info{
+			Title:       opt.Title,
+			Version:     opt.Version,
+			Description: opt.Description,
+		},
+		Paths: make(map[string]*openapi.PathItem),
+	}
+
+	for _, url := range opt.Servers {
+		doc.Servers = append(doc.Servers, openapi.Server{URL: url})
+	}
+
+	gen := &schemaGen{schemas: make(map[string]*openapi.Schema), names: make(map[reflect.Type]string), envelope: mn.envelope}
+	opIDs := make(map[string]bool)
+	tags := make(map[string]bool)
+	for _, route := range mn.routes {
+		if route.Kind == RouteMount || route.Path == opt.Path || route.Path == opt.DocsPath || route.Path == redocScriptPath(opt.DocsPath) {
+			continue
+		}
+
+		path := openAPIPath(route.Path)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = new(openapi.PathItem)
+			doc.Paths[path] = item
+		}
+
+		// the same route with other version, selected by header
+		if op := item.Operation(route.Method); op != nil {
+			mn.addVersionParam(op, route.Version)
+			continue
+		}
+
+		op := mn.operation(gen, route)
+		op.OperationID = uniqueID(opIDs, operationID(route))
+		for _, tag := range op.Tags {
+			tags[tag] = true
+		}
+
+		item.SetOperation(route.Method, op)
+	}
+
+	for tag := range tags {
+		doc.Tags = append(doc.Tags, openapi.Tag{Name: tag})
+	}
+
+	sort.Slice(doc.Tags, func(i, j int) bool {
+		return doc.Tags[i].Name < doc.Tags[j].Name
+	})
+
+	if len(gen.schemas) > 0 {
+		doc.Components = &openapi.Components{Schemas: gen.schemas}
+	}
+
+	return doc
+}
+
+func (mn *Minirest) operation(gen *schemaGen, route RouteInfo) *openapi.Operation {
+	op := &openapi.Operation{Responses: make(map[string]*openapi.Response)}
+	doc := route.Doc
+	if doc == nil {
+		doc = new(EndpointDoc)
+	}
+
+	op.Summary = doc.Summary
+	op.Description = doc.Description
+	op.Deprecated = doc.Deprecated
+	op.Tags = doc.Tags
+	if op.Tags == nil && route.Controller != "" {
+		op.Tags = []string{route.Controller}
+	}
+
+	if _, ok := mn.versioning.Deprecated[route.Version]; ok && route.Version != "" {
+		op.Deprecated = true
+	}
+
+	// every path variable must be declared, even when it's not bound to callback parameter
+	declared := make(map[string]bool)
+	bindErr := false
+	for _, binding := range route.Bindings {
+		switch binding.Source {
+		case BindPath:
+			declared[binding.Name] = true
+			op.Parameters = append(op.Parameters, &openapi.Parameter{
+				Name:     binding.Name,
+				In:       openapi.InPath,
+				Required: true,
+				Schema:   gen.schema(binding.typ),
+			})
+
+			bindErr = bindErr || binding.typ.Kind() != reflect.String
+		case BindQuery:
+			bindErr = true
+			// named query is single field bound by typed handler
+			if binding.Name != "" {
+				op.Parameters = append(op.Parameters, &openapi.Parameter{
+					Name:     binding.Name,
+					In:       openapi.InQuery,
+					Required: binding.required,
+					Schema:   gen.schema(binding.typ),
+				})
+
+				continue
+			}
+
+			op.Parameters = append(op.Parameters, gen.queryParams(binding.typ, "")...)
+		case BindBody:
+			op.RequestBody = &openapi.RequestBody{
+				Required: true,
+				Content:  map[string]openapi.MediaType{"application/json": {Schema: gen.schema(binding.typ)}},
+			}
+
+			bindErr = true
+		}
+	}
+
+	for _, name := range pathVars(route.Path) {
+		if !declared[name] {
+			op.Parameters = append(op.Parameters, &openapi.Parameter{
+				Name:     name,
+				In:       openapi.InPath,
+				Required: true,
+				Schema:   &openapi.Schema{Type: "string"},
+			})
+		}
+	}
+
+	if route.Version != "" {
+		mn.addVersionParam(op, route.Version)
+	}
+
+	switch route.Kind {
+	case RouteSSE:
+		op.Responses["200"] = &openapi.Response{
+			Description: "Server-Sent Events stream",
+			Content:     map[string]openapi.MediaType{"text/event-stream": {Schema: &openapi.Schema{Type: "string"}}},
+		}
+	case RouteWebSocket:
+		op.Responses["101"] = &openapi.Response{Description: "Switching Protocols to WebSocket"}
+	case RouteHandler:
+		op.Responses["default"] = &openapi.Response{Description: "Response of handler"}
+	default:
+		status := doc.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		var body *openapi.Schema
+		if doc.Response != nil {
+			body = gen.schema(reflect.TypeOf(doc.Response))
+		}
+
+		op.Responses[strconv.Itoa(status)] = gen.response(status, body)
+	}
+
+	errs := doc.Errors
+	if bindErr {
+		errs = append([]int{http.StatusBadRequest}, errs...)
+	}
+
+	for _, code := range errs {
+		if _, ok := op.Responses[strconv.Itoa(code)]; !ok {
+			op.Responses[strconv.Itoa(code)] = gen.response(code, nil)
+		}
+	}
+
+	return op
+}
+
+// addVersionParam document version of op selected by header.
+// Versions selected by path prefix have their own paths, and Accept can't be described as parameter
+func (mn *Minirest) addVersionParam(op *openapi.Operation, version string) {
+	if mn.versioning.Strategy != VersionByHeader || version == "" {
+		return
+	}
+
+	for _, param := range op.Parameters {
+		if param.In == openapi.InHeader && param.Name == mn.versioning.Header {
+			param.Schema.Enum = append(param.Schema.Enum, version)
+			return
+		}
+	}
+
+	op.Parameters = append(op.Parameters, &openapi.Parameter{
+		Name:     mn.versioning.Header,
+		In:       openapi.InHeader,
+		Required: mn.versioning.Default == "",
+		Schema:   &openapi.Schema{Type: "string", Enum: []interface{}{version}},
+	})
+}
+
+// openAPIPath convert router path into OpenAPI path template, such as /users/:id into /users/{id}
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if isPathVar(segment) {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// operationID return id of operation, such as userControllerGet
+func operationID(route RouteInfo) string {
+	handler := route.Handler[strings.LastIndex(route.Handler, ".")+1:]
+	id := route.Controller + handler
+	// anonymous functions are named func1, func2 and so on
+	if handler == "" || strings.HasPrefix(handler, "func") && strings.Trim(handler[4:], "0123456789") == "" {
+		id = strings.ToLower(route.Method)
+		for _, segment := range strings.Split(route.Path, "/") {
+			segment = strings.TrimLeft(segment, ":*")
+			if segment != "" {
+				id += strings.ToUpper(segment[:1]) + segment[1:]
+			}
+		}
+	}
+
+	var sb strings.Builder
+	for _, r := range id {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			continue
+		}
+
+		if sb.Len() == 0 {
+			r = unicode.ToLower(r)
+		}
+
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
+
+func uniqueID(used map[string]bool, id string) string {
+	unique := id
+	for i := 2; used[unique]; i++ {
+		unique = id + strconv.Itoa(i)
+	}
+
+	used[unique] = true
+	return unique
+}
+
+// schemaGen generate schemas of Go types, named structs are put into components
+type schemaGen struct {
+	schemas  map[string]*openapi.Schema
+	names    map[reflect.Type]string
+	envelope Envelope
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (gen *schemaGen) schema(t reflect.Type) *openapi.Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return &openapi.Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &openapi.Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &openapi.Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64:
+		// int is 64-bit on every supported target
+		return &openapi.Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		// unsigned integers don't fit in int32 of the same size, and OpenAPI has no unsigned formats
+		minimum := 0.0
+		return &openapi.Schema{Type: "integer", Format: "int64", Minimum: &minimum}
+	case reflect.Float32:
+		return &openapi.Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &openapi.Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &openapi.Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &openapi.Schema{Type: "string", Format: "byte"}
+		}
+
+		return &openapi.Schema{Type: "array", Items: gen.schema(t.Elem())}
+	case reflect.Map:
+		return &openapi.Schema{Type: "object", AdditionalProperties: gen.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return gen.structSchema(t)
+		}
+
+		return &openapi.Schema{Ref: openapi.RefPrefix + gen.component(t)}
+	}
+
+	// interface{} and other types accept any value
+	return &openapi.Schema{}
+}
+
+// component register named struct t into components and return its name
+func (gen *schemaGen) component(t reflect.Type) string {
+	if name, ok := gen.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, ok := gen.schemas[name]; ok {
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		if pkg != "" {
+			pkg = strings.ToUpper(pkg[:1]) + pkg[1:]
+		}
+
+		name = uniqueSchemaName(gen.schemas, pkg+name)
+	}
+
+	// register before generating, so recursive types refer to themselves
+	gen.names[t] = name
+	gen.schemas[name] = nil
+	gen.schemas[name] = gen.structSchema(t)
+
+	return name
+}
+
+func uniqueSchemaName(schemas map[string]*openapi.Schema, name string) string {
+	unique := name
+	for i := 2; ; i++ {
+		if _, ok := schemas[unique]; !ok {
+			return unique
+		}
+
+		unique = name + strconv.Itoa(i)
+	}
+}
+
+// structSchema generate schema of struct t following encoding/json rules.
+// Fields without omitempty are required
+func (gen *schemaGen) structSchema(t reflect.Type) *openapi.Schema {
+	schema := &openapi.Schema{Type: "object", Properties: make(map[string]*openapi.Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts := parseTag(field.Tag.Get("json"))
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		// fields bound from path variables and url queries are not part of body
+		if _, ok := field.Tag.Lookup("path"); ok {
+			continue
+		}
+
+		if _, ok := field.Tag.Lookup("query"); ok {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				embedded := gen.structSchema(ft)
+				for key, prop := range embedded.Properties {
+					if _, ok := schema.Properties[key]; !ok {
+						schema.Properties[key] = prop
+					}
+				}
+
+				schema.Required = append(schema.Required, embedded.Required...)
+				continue
+			}
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		schema.Properties[name] = gen.schema(field.Type)
+		if !strings.Contains(","+opts+",", ",omitempty,") && field.Type.Kind() != reflect.Ptr {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return schema
+}
+
+// queryParams generate query parameters of struct t following gorilla/schema rules.
+// Nested structs are flattened into dotted names
+func (gen *schemaGen) queryParams(t reflect.Type, prefix string) []*openapi.Parameter {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var params []*openapi.Parameter
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		name, opts := parseTag(field.Tag.Get("schema"))
+		if name == "-" {
+			continue
+		}
+
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if ft.Kind() == reflect.Struct && ft != timeType {
+			nested := prefix
+			if !field.Anonymous {
+				if name == "" {
+					name = field.Name
+				}
+
+				nested += name + "."
+			}
+
+			params = append(params, gen.queryParams(ft, nested)...)
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		params = append(params, &openapi.Parameter{
+			Name:     prefix + name,
+			In:       openapi.InQuery,
+			Required: strings.Contains(","+opts+",", ",required,"),
+			Schema:   gen.schema(field.Type),
+		})
+	}
+
+	return params
+}
+
+// response describe response written by the configured envelope, with data described by body
+func (gen *schemaGen) response(code int, body *openapi.Schema) *openapi.Response {
+	desc := http.StatusText(code)
+	if desc == "" {
+		desc = StatusMessage(code)
+	}
+
+	if !bodyAllowedForStatus(code) {
+		return &openapi.Response{Description: desc}
+	}
+
+	contentType, schema := gen.envelopeSchema(gen.envelope, code, body)
+	if schema == nil {
+		return &openapi.Response{Description: desc}
+	}
+
+	return &openapi.Response{
+		Description: desc,
+		Content:     map[string]openapi.MediaType{contentType: {Schema: schema}},
+	}
+}
+
+// envelopeSchema return content type and schema of body written by env, nil schema means no body.
+// Error responses of DefaultEnvelope without body refer to Response schema in components,
+// custom envelopes are described as any JSON value
+func (gen *schemaGen) envelopeSchema(env Envelope, code int, body *openapi.Schema) (string, *openapi.Schema) {
+	switch env := env.(type) {
+	case nil, defaultEnvelope:
+		if body == nil && code >= 400 {
+			return "application/json", gen.schema(reflect.TypeOf(Response{}))
+		}
+
+		schema := &openapi.Schema{
+			Type: "object",
+			Properties: map[string]*openapi.Schema{
+				"statusCode":  {Type: "integer", Format: "int32"},
+				"status":      {Type: "string"},
+				"description": {Type: "string"},
+			},
+			Required: []string{"statusCode", "status"},
+		}
+
+		if body != nil {
+			schema.Properties["body"] = body
+		}
+
+		return "application/json", schema
+
+	case rawEnvelope:
+		if body != nil {
+			return "application/json", body
+		}
+
+		// description is written instead of missing data
+		return "application/json", &openapi.Schema{Type: "string"}
+
+	case jsonAPIEnvelope:
+		if code >= 400 {
+			meta := body
+			if meta == nil {
+				meta = &openapi.Schema{}
+			}
+
+			return "application/vnd.api+json", &openapi.Schema{
+				Type: "object",
+				Properties: map[string]*openapi.Schema{
+					"errors": {Type: "array", Items: &openapi.Schema{
+						Type: "object",
+						Properties: map[string]*openapi.Schema{
+							"status": {Type: "string"},
+							"code":   {Type: "string"},
+							"detail": {Type: "string"},
+							"meta":   meta,
+						},
+						Required: []string{"status"},
+					}},
+				},
+				Required: []string{"errors"},
+			}
+		}
+
+		schema := &openapi.Schema{
+			Type: "object",
+			Properties: map[string]*openapi.Schema{
+				"meta": {Type: "object", Properties: map[string]*openapi.Schema{"description": {Type: "string"}}},
+			},
+		}
+
+		if body != nil {
+			schema.Properties["data"] = body
+			schema.Required = []string{"data"}
+		}
+
+		return "application/vnd.api+json", schema
+
+	case problemEnvelope:
+		if code >= 400 {
+			return "application/problem+json", gen.schema(reflect.TypeOf(Problem{}))
+		}
+
+		return gen.envelopeSchema(env.success, code, body)
+	}
+
+	return "application/json", &openapi.Schema{}
+}
+
+func parseTag(tag string) (string, string) {
+	if i := strings.Index(tag, ","); i != -1 {
+		return tag[:i], tag[i+1:]
+	}
+
+	return tag, ""
+}
+
+func htmlEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&#34;").Replace(s)
+}
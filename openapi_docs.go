@@ -0,0 +1,145 @@
+package minirest
+
+// docsPage is self-contained page rendering OpenAPI document fetched from {{spec}}
+const docsPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 0; color: #222; background: #fafafa; }
+header { background: #1f2933; color: #fff; padding: 16px 32px; }
+header h1 { margin: 0; font-size: 22px; }
+header p { margin: 4px 0 0; color: #cbd2d9; }
+main { max-width: 1000px; margin: 0 auto; padding: 16px 32px; }
+h2 { border-bottom: 1px solid #ddd; padding-bottom: 4px; }
+details { background: #fff; border: 1px solid #ddd; border-radius: 4px; margin: 8px 0; }
+summary { cursor: pointer; padding: 8px 12px; font-family: monospace; font-size: 14px; }
+.method { display: inline-block; width: 64px; font-weight: bold; color: #fff; text-align: center; border-radius: 3px; margin-right: 8px; }
+.GET { background: #2f80ed; } .POST { background: #27ae60; } .PUT { background: #f2994a; }
+.PATCH { background: #9b51e0; } .DELETE { background: #eb5757; } .HEAD, .OPTIONS, .TRACE { background: #828282; }
+.deprecated { text-decoration: line-through; color: #888; }
+.body { padding: 0 12px 12px; }
+table { border-collapse: collapse; width: 100%; font-size: 13px; }
+td, th { border: 1px solid #eee; padding: 4px 8px; text-align: left; }
+pre { background: #f4f4f4; padding: 8px; overflow: auto; font-size: 12px; }
+</style>
+</head>
+<body>
+<header><h1 id="title">{{title}}</h1><p id="description"></p></header>
+<main id="content">Loading...</main>
+<script>
+(function () {
+  var spec = {{spec}};
+  var el = function (tag, attrs, children) {
+    var node = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function (key) { node.setAttribute(key, attrs[key]); });
+    (children || []).forEach(function (child) {
+      node.appendChild(typeof child === "string" ? document.createTextNode(child) : child);
+    });
+    return node;
+  };
+
+  var resolve = function (doc, schema, depth) {
+    if (!schema || depth > 6) return schema;
+    if (schema.$ref) {
+      var name = schema.$ref.split("/").pop();
+      return resolve(doc, doc.components.schemas[name], depth + 1);
+    }
+    var out = {};
+    Object.keys(schema).forEach(function (key) { out[key] = schema[key]; });
+    if (out.items) out.items = resolve(doc, out.items, depth + 1);
+    if (out.additionalProperties) out.additionalProperties = resolve(doc, out.additionalProperties, depth + 1);
+    if (out.properties) {
+      out.properties = {};
+      Object.keys(schema.properties).forEach(function (key) {
+        out.properties[key] = resolve(doc, schema.properties[key], depth + 1);
+      });
+    }
+    return out;
+  };
+
+  var schemaBlock = function (doc, content) {
+    var media = content && content["application/json"];
+    if (!media || !media.schema) return el("span");
+    return el("pre", {}, [JSON.stringify(resolve(doc, media.schema, 0), null, 2)]);
+  };
+
+  var render = function (doc) {
+    document.title = doc.info.title || document.title;
+    document.getElementById("title").textContent = (doc.info.title || "API") + " " + (doc.info.version || "");
+    document.getElementById("description").textContent = doc.info.description || "";
+    var groups = {};
+    Object.keys(doc.paths).sort().forEach(function (path) {
+      var item = doc.paths[path];
+      ["get", "put", "post", "delete", "options", "head", "patch", "trace"].forEach(function (method) {
+        var op = item[method];
+        if (!op) return;
+        var tag = (op.tags && op.tags[0]) || "default";
+        (groups[tag] = groups[tag] || []).push({ path: path, method: method.toUpperCase(), op: op });
+      });
+    });
+
+    var content = document.getElementById("content");
+    content.textContent = "";
+    Object.keys(groups).sort().forEach(function (tag) {
+      content.appendChild(el("h2", {}, [tag]));
+      groups[tag].forEach(function (entry) {
+        var op = entry.op;
+        var body = el("div", { "class": "body" });
+        if (op.description) body.appendChild(el("p", {}, [op.description]));
+        if (op.parameters && op.parameters.length) {
+          var rows = op.parameters.map(function (param) {
+            return el("tr", {}, [
+              el("td", {}, [param.name]), el("td", {}, [param.in]),
+              el("td", {}, [(param.schema && (param.schema.type || "any")) || ""]),
+              el("td", {}, [param.required ? "required" : ""])
+            ]);
+          });
+          body.appendChild(el("h4", {}, ["Parameters"]));
+          body.appendChild(el("table", {}, [el("tr", {}, [el("th", {}, ["Name"]), el("th", {}, ["In"]), el("th", {}, ["Type"]), el("th", {}, [""])])].concat(rows)));
+        }
+        if (op.requestBody) {
+          body.appendChild(el("h4", {}, ["Request body"]));
+          body.appendChild(schemaBlock(doc, op.requestBody.content));
+        }
+        body.appendChild(el("h4", {}, ["Responses"]));
+        Object.keys(op.responses).sort().forEach(function (code) {
+          var resp = op.responses[code];
+          body.appendChild(el("div", {}, [el("strong", {}, [code + " "]), resp.description]));
+          body.appendChild(schemaBlock(doc, resp.content));
+        });
+        var title = el("span", { "class": op.deprecated ? "deprecated" : "" }, [entry.path]);
+        content.appendChild(el("details", {}, [
+          el("summary", {}, [el("span", { "class": "method " + entry.method }, [entry.method]), title, " " + (op.summary || "")]),
+          body
+        ]));
+      });
+    });
+  };
+
+  fetch(spec).then(function (resp) { return resp.json(); }).then(render).catch(function (err) {
+    document.getElementById("content").textContent = "Failed to load " + spec + ": " + err;
+  });
+})();
+</script>
+</body>
+</html>
+`
+
+// redocPage render OpenAPI document fetched from {{spec}} with Redoc bundle served at {{script}}
+const redocPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{title}}</title>
+<style>body { margin: 0; }</style>
+</head>
+<body>
+<redoc spec-url="{{spec}}"></redoc>
+<script src="{{script}}"></script>
+</body>
+</html>
+`
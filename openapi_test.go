@@ -0,0 +1,173 @@
+package minirest
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tamboto2000/minirest/openapi"
+)
+
+// documentUser register documented GET /users/{id}
+func documentUser(ep *Endpoints) {
+	ep.GET("/users/:id", func(id int) *ResponseBuilder { return echo(id) })
+	ep.Doc("GET", "/users/:id", EndpointDoc{Response: testBody{}, Errors: []int{404}})
+}
+
+// responseSchema return content type and schema of response with status code of GET /users/{id}
+func responseSchema(t *testing.T, doc *openapi.Document, code string) (string, *openapi.Schema) {
+	t.Helper()
+	item := doc.Paths["/users/{id}"]
+	if item == nil || item.Get == nil {
+		t.Fatalf("GET /users/{id} is not documented")
+	}
+
+	resp := item.Get.Responses[code]
+	if resp == nil || len(resp.Content) != 1 {
+		t.Fatalf("response %s = %+v", code, resp)
+	}
+
+	for contentType, media := range resp.Content {
+		return contentType, media.Schema
+	}
+
+	return "", nil
+}
+
+func TestOpenAPIEnvelope(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        Envelope
+		okType     string
+		okProps    []string
+		errType    string
+		errRef     string
+		errProps   []string
+		okIsBody   bool
+		okIsAnyVal bool
+	}{
+		{name: "default", okType: "application/json", okProps: []string{"statusCode", "status", "description", "body"},
+			errType: "application/json", errRef: "#/components/schemas/Response"},
+		{name: "raw", env: RawEnvelope, okType: "application/json", okIsBody: true,
+			errType: "application/json"},
+		{name: "json api", env: JSONAPIEnvelope, okType: "application/vnd.api+json", okProps: []string{"data", "meta"},
+			errType: "application/vnd.api+json", errProps: []string{"errors"}},
+		{name: "problem", env: ProblemEnvelope(RawEnvelope), okType: "application/json", okIsBody: true,
+			errType: "application/problem+json", errRef: "#/components/schemas/Problem"},
+		{name: "custom", env: EnvelopeFunc(func(r *http.Request, resp Response) (string, interface{}) { return "", nil }),
+			okType: "application/json", okIsAnyVal: true, errType: "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mn := newEndpointsApp(documentUser)
+			mn.ResponseEnvelope(tt.env)
+			doc := mn.OpenAPIDocument(OpenAPIOption{Title: "Test"})
+			contentType, schema := responseSchema(t, doc, "200")
+			if contentType != tt.okType {
+				t.Errorf("200 content type = %q, want %q", contentType, tt.okType)
+			}
+
+			for _, prop := range tt.okProps {
+				if schema.Properties[prop] == nil {
+					t.Errorf("200 schema has no property %q: %+v", prop, schema)
+				}
+			}
+
+			if tt.okIsBody && schema.Ref != "#/components/schemas/testBody" {
+				t.Errorf("200 schema = %+v, want data schema", schema)
+			}
+
+			if tt.okIsAnyVal && (schema.Type != "" || schema.Ref != "") {
+				t.Errorf("200 schema = %+v, want any value", schema)
+			}
+
+			contentType, schema = responseSchema(t, doc, "404")
+			if contentType != tt.errType {
+				t.Errorf("404 content type = %q, want %q", contentType, tt.errType)
+			}
+
+			if tt.errRef != "" && schema.Ref != tt.errRef {
+				t.Errorf("404 schema ref = %q, want %q", schema.Ref, tt.errRef)
+			}
+
+			for _, prop := range tt.errProps {
+				if schema.Properties[prop] == nil {
+					t.Errorf("404 schema has no property %q: %+v", prop, schema)
+				}
+			}
+
+			if tt.errRef != "" && doc.Components.Schemas[strings.TrimPrefix(tt.errRef, "#/components/schemas/")] == nil {
+				t.Errorf("%s is not in components", tt.errRef)
+			}
+		})
+	}
+}
+
+func TestOpenAPIIntegerSchema(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		format  string
+		minimum bool
+	}{
+		{value: int8(0), format: "int32"},
+		{value: int32(0), format: "int32"},
+		{value: 0, format: "int64"},
+		{value: int64(0), format: "int64"},
+		{value: uint8(0), format: "int64", minimum: true},
+		{value: uint32(0), format: "int64", minimum: true},
+		{value: uint(0), format: "int64", minimum: true},
+	}
+
+	gen := &schemaGen{schemas: make(map[string]*openapi.Schema), names: make(map[reflect.Type]string)}
+	for _, tt := range tests {
+		typ := reflect.TypeOf(tt.value)
+		got := gen.schema(typ)
+		if got.Type != "integer" || got.Format != tt.format || (got.Minimum != nil) != tt.minimum || (tt.minimum && *got.Minimum != 0) {
+			t.Errorf("schema of %s = %+v, want format %s", typ, got, tt.format)
+		}
+	}
+}
+
+func TestOpenAPIDocsPage(t *testing.T) {
+	mn := newEndpointsApp(documentUser)
+	mn.OpenAPI(OpenAPIOption{Title: "Pets <API>"})
+
+	w := serve(mn, "GET", "/docs", nil)
+	if w.Code != 200 || w.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("status = %d, Content-Type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	// title is put in both <title> and <h1>
+	page := w.Body.String()
+	if strings.Count(page, "Pets &lt;API&gt;") != 2 || strings.Contains(page, "{{") {
+		t.Errorf("docs page is not filled:\n%s", page)
+	}
+
+	if w := serve(mn, "GET", "/openapi.json", nil); w.Code != 200 || !strings.Contains(w.Body.String(), `"/users/{id}"`) {
+		t.Errorf("document status = %d, body %s", w.Code, w.Body)
+	}
+}
+
+func TestOpenAPIRedoc(t *testing.T) {
+	mn := newEndpointsApp(documentUser)
+	mn.OpenAPI(OpenAPIOption{Title: "Pets", Path: "/spec.json", RedocScript: []byte("/* redoc */")})
+
+	page := serve(mn, "GET", "/docs", nil).Body.String()
+	for _, want := range []string{`<redoc spec-url="/spec.json">`, `<script src="/docs/redoc.standalone.js">`, "<title>Pets</title>"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("docs page doesn't contain %q:\n%s", want, page)
+		}
+	}
+
+	w := serve(mn, "GET", "/docs/redoc.standalone.js", nil)
+	if w.Code != 200 || w.Body.String() != "/* redoc */" || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/javascript") {
+		t.Errorf("script status = %d, Content-Type %q, body %q", w.Code, w.Header().Get("Content-Type"), w.Body)
+	}
+
+	doc := mn.OpenAPIDocument(OpenAPIOption{Path: "/spec.json"})
+	if len(doc.Paths) != 1 {
+		t.Errorf("document paths = %v, want only /users/{id}", doc.Paths)
+	}
+}
@@ -2,34 +2,118 @@ package minirest
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // HTTP status codes
 const (
-	CodeOk               = 200
-	CodeNoContent        = 204
-	CodeBadRequest       = 400
-	CodeNotFound         = 404
-	CodeMethodNotAllowed = 405
-	CodeTooManyRequest   = 429
-	CodeInternalError    = 500
-	CodeOverload         = 503
+	CodeOk                   = 200
+	CodeCreated              = 201
+	CodeAccepted             = 202
+	CodeNoContent            = 204
+	CodeMovedPermanently     = 301
+	CodeFound                = 302
+	CodeNotModified          = 304
+	CodeTemporaryRedirect    = 307
+	CodePermanentRedirect    = 308
+	CodeBadRequest           = 400
+	CodeUnauthorized         = 401
+	CodeForbidden            = 403
+	CodeNotFound             = 404
+	CodeMethodNotAllowed     = 405
+	CodeConflict             = 409
+	CodeGone                 = 410
+	CodePreconditionFailed   = 412
+	CodeUnsupportedMediaType = 415
+	CodeUnprocessableEntity  = 422
+	CodeTooManyRequest       = 429
+	CodeInternalError        = 500
+	CodeOverload             = 503
+	CodeGatewayTimeout       = 504
 )
 
 // HTTP status message
 const (
-	MsgOk               = "ok"
-	MsgNoContent        = "no_content"
-	MsgBadRequest       = "bad_request"
-	MsgNotFound         = "not_found"
-	MsgMethodNotAllowed = "method_not_allowed"
-	MsgTooManyRequest   = "too_many_request"
-	MsgInternalError    = "internal_error"
-	MsgOverloadError    = "server_overload"
+	MsgOk                   = "ok"
+	MsgCreated              = "created"
+	MsgAccepted             = "accepted"
+	MsgNoContent            = "no_content"
+	MsgMovedPermanently     = "moved_permanently"
+	MsgFound                = "found"
+	MsgNotModified          = "not_modified"
+	MsgTemporaryRedirect    = "temporary_redirect"
+	MsgPermanentRedirect    = "permanent_redirect"
+	MsgBadRequest           = "bad_request"
+	MsgUnauthorized         = "unauthorized"
+	MsgForbidden            = "forbidden"
+	MsgNotFound             = "not_found"
+	MsgMethodNotAllowed     = "method_not_allowed"
+	MsgConflict             = "conflict"
+	MsgGone                 = "gone"
+	MsgPreconditionFailed   = "precondition_failed"
+	MsgUnsupportedMediaType = "unsupported_media_type"
+	MsgUnprocessableEntity  = "unprocessable_entity"
+	MsgTooManyRequest       = "too_many_request"
+	MsgInternalError        = "internal_error"
+	MsgOverloadError        = "server_overload"
+	MsgGatewayTimeout       = "gateway_timeout"
 )
 
+var statusMessages = map[int]string{
+	CodeOk:                   MsgOk,
+	CodeCreated:              MsgCreated,
+	CodeAccepted:             MsgAccepted,
+	CodeNoContent:            MsgNoContent,
+	CodeMovedPermanently:     MsgMovedPermanently,
+	CodeFound:                MsgFound,
+	CodeNotModified:          MsgNotModified,
+	CodeTemporaryRedirect:    MsgTemporaryRedirect,
+	CodePermanentRedirect:    MsgPermanentRedirect,
+	CodeBadRequest:           MsgBadRequest,
+	CodeUnauthorized:         MsgUnauthorized,
+	CodeForbidden:            MsgForbidden,
+	CodeNotFound:             MsgNotFound,
+	CodeMethodNotAllowed:     MsgMethodNotAllowed,
+	CodeConflict:             MsgConflict,
+	CodeGone:                 MsgGone,
+	CodePreconditionFailed:   MsgPreconditionFailed,
+	CodeUnsupportedMediaType: MsgUnsupportedMediaType,
+	CodeUnprocessableEntity:  MsgUnprocessableEntity,
+	CodeTooManyRequest:       MsgTooManyRequest,
+	CodeInternalError:        MsgInternalError,
+	CodeOverload:             MsgOverloadError,
+	CodeGatewayTimeout:       MsgGatewayTimeout,
+}
+
+// StatusMessage return status message for HTTP status code code.
+// For code without Msg constant, the message is derived from http.StatusText,
+// for example 418 become "im_a_teapot"
+func StatusMessage(code int) string {
+	if msg, ok := statusMessages[code]; ok {
+		return msg
+	}
+
+	text := strings.ToLower(http.StatusText(code))
+	if text == "" {
+		return "unknown"
+	}
+
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			return r
+		}
+
+		if r == ' ' || r == '-' {
+			return '_'
+		}
+
+		return -1
+	}, text)
+}
+
 // Response is body for HTTP response
 type Response struct {
 	StatusCode  int         `json:"statusCode"`
@@ -38,13 +122,44 @@ type Response struct {
 	Body        interface{} `json:"body,omitempty"`
 }
 
+// header operations, applied in order when response is written
+const (
+	headerSet = iota
+	headerAdd
+	headerDel
+)
+
+type headerOp struct {
+	op    int
+	key   string
+	value string
+}
+
+// NewCookie return cookie with secure defaults: Path "/", HttpOnly, Secure and SameSite Lax
+func NewCookie(name, value string) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
 // ResponseBuilder is a response builder
 type ResponseBuilder struct {
 	// Set to true for returning gzip encoded response
 	Gzip       bool
 	statusCode int
-	headers    [][2]string
+	headers    []headerOp
 	body       interface{}
+	// response built by helpers, formatted by Envelope on write
+	response     *Response
+	etag         string
+	etagMode     ETagMode
+	lastModified time.Time
+	cacheTags    []string
 }
 
 // Status set status code
@@ -53,134 +168,399 @@ func (resp *ResponseBuilder) Status(code int) *ResponseBuilder {
 	return resp
 }
 
-// Headers add headers
+// Headers add headers.
+// Headers can be called multiple times, values are added to previous headers
 func (resp *ResponseBuilder) Headers(headers [][2]string) *ResponseBuilder {
-	resp.headers = headers
+	for _, header := range headers {
+		resp.AddHeader(header[0], header[1])
+	}
 
 	return resp
 }
 
-// Body set body
+// SetHeader set header key to value, replacing existing values,
+// including the ones set by middlewares
+func (resp *ResponseBuilder) SetHeader(key, value string) *ResponseBuilder {
+	resp.headers = append(resp.headers, headerOp{headerSet, key, value})
+
+	return resp
+}
+
+// AddHeader add value to header key
+func (resp *ResponseBuilder) AddHeader(key, value string) *ResponseBuilder {
+	resp.headers = append(resp.headers, headerOp{headerAdd, key, value})
+
+	return resp
+}
+
+// DelHeader delete header key, including the one set by middlewares
+func (resp *ResponseBuilder) DelHeader(key string) *ResponseBuilder {
+	resp.headers = append(resp.headers, headerOp{headerDel, key, ""})
+
+	return resp
+}
+
+// SetCookie add cookie name with secure defaults, see NewCookie.
+// Use AddCookie for cookie with other attributes, such as cookie that isn't Secure
+func (resp *ResponseBuilder) SetCookie(name, value string) *ResponseBuilder {
+	return resp.AddCookie(NewCookie(name, value))
+}
+
+// AddCookie add cookie to response with its attributes as is.
+// cookie is encoded immediately, later changes to it doesn't affect the response
+func (resp *ResponseBuilder) AddCookie(cookie *http.Cookie) *ResponseBuilder {
+	if v := cookie.String(); v != "" {
+		resp.AddHeader("Set-Cookie", v)
+	}
+
+	return resp
+}
+
+// ClearCookie tell client to delete cookie name with path "/"
+func (resp *ResponseBuilder) ClearCookie(name string) *ResponseBuilder {
+	cookie := NewCookie(name, "")
+	cookie.MaxAge = -1
+	cookie.Expires = time.Unix(0, 0)
+
+	return resp.AddCookie(cookie)
+}
+
+// Body set body.
+// Body is written as is, without Envelope
 func (resp *ResponseBuilder) Body(body interface{}) *ResponseBuilder {
 	resp.body = body
+	resp.response = nil
 
 	return resp
 }
 
 // Ok build response with HTTP Status 200
 func (resp *ResponseBuilder) Ok(data interface{}) *ResponseBuilder {
-	resp.statusCode = CodeOk
-	resp.body = Response{
-		StatusCode: CodeOk,
-		Status:     MsgOk,
-		Body:       data,
+	return resp.envelope(CodeOk, MsgOk, "", data)
+}
+
+// Created build response with HTTP Status 201.
+// location is set as Location header if not empty
+func (resp *ResponseBuilder) Created(location string, data interface{}) *ResponseBuilder {
+	if location != "" {
+		resp.SetHeader("Location", location)
 	}
 
-	return resp
+	return resp.envelope(CodeCreated, MsgCreated, "", data)
 }
 
-// NoContent build response with HTTP Status 204
+// Accepted build response with HTTP Status 202
+func (resp *ResponseBuilder) Accepted(data interface{}) *ResponseBuilder {
+	return resp.envelope(CodeAccepted, MsgAccepted, "", data)
+}
+
+// NoContent build response with HTTP Status 204.
+// Response with status 204 has no body, so desc is not sent to client
 func (resp *ResponseBuilder) NoContent(desc string) *ResponseBuilder {
-	resp.statusCode = CodeNoContent
-	resp.body = Response{
-		StatusCode:  CodeNoContent,
-		Status:      MsgNoContent,
-		Description: desc,
-	}
+	return resp.envelope(CodeNoContent, MsgNoContent, desc, nil)
+}
 
-	return resp
+// MovedPermanently build redirect response with HTTP Status 301
+func (resp *ResponseBuilder) MovedPermanently(location string) *ResponseBuilder {
+	return resp.Redirect(CodeMovedPermanently, location)
+}
+
+// Found build redirect response with HTTP Status 302
+func (resp *ResponseBuilder) Found(location string) *ResponseBuilder {
+	return resp.Redirect(CodeFound, location)
+}
+
+// NotModified build response with HTTP Status 304, without body
+func (resp *ResponseBuilder) NotModified() *ResponseBuilder {
+	return resp.envelope(CodeNotModified, MsgNotModified, "", nil)
+}
+
+// TemporaryRedirect build redirect response with HTTP Status 307
+func (resp *ResponseBuilder) TemporaryRedirect(location string) *ResponseBuilder {
+	return resp.Redirect(CodeTemporaryRedirect, location)
+}
+
+// PermanentRedirect build redirect response with HTTP Status 308
+func (resp *ResponseBuilder) PermanentRedirect(location string) *ResponseBuilder {
+	return resp.Redirect(CodePermanentRedirect, location)
+}
+
+// Redirect build redirect response with status code code and Location header location
+func (resp *ResponseBuilder) Redirect(code int, location string) *ResponseBuilder {
+	resp.SetHeader("Location", location)
+
+	return resp.envelope(code, StatusMessage(code), "", nil)
 }
 
 // BadRequest build response with HTTP Status 400
 func (resp *ResponseBuilder) BadRequest(desc string) *ResponseBuilder {
-	resp.statusCode = CodeBadRequest
-	resp.body = Response{
-		StatusCode:  CodeBadRequest,
-		Status:      MsgBadRequest,
-		Description: desc,
-	}
+	return resp.envelope(CodeBadRequest, MsgBadRequest, desc, nil)
+}
 
-	return resp
+// Unauthorized build response with HTTP Status 401
+func (resp *ResponseBuilder) Unauthorized(desc string) *ResponseBuilder {
+	return resp.envelope(CodeUnauthorized, MsgUnauthorized, desc, nil)
+}
+
+// Forbidden build response with HTTP Status 403
+func (resp *ResponseBuilder) Forbidden(desc string) *ResponseBuilder {
+	return resp.envelope(CodeForbidden, MsgForbidden, desc, nil)
 }
 
 // NotFound build response with HTTP Status 404
 func (resp *ResponseBuilder) NotFound(desc string) *ResponseBuilder {
-	resp.statusCode = CodeNotFound
-	resp.body = Response{
-		StatusCode:  CodeNotFound,
-		Status:      MsgNotFound,
-		Description: desc,
-	}
-
-	return resp
+	return resp.envelope(CodeNotFound, MsgNotFound, desc, nil)
 }
 
 // MethodNotAllowed build response with HTTP Status 405
 func (resp *ResponseBuilder) MethodNotAllowed(desc string) *ResponseBuilder {
-	resp.statusCode = CodeMethodNotAllowed
-	resp.body = Response{
-		StatusCode:  CodeMethodNotAllowed,
-		Status:      MsgMethodNotAllowed,
-		Description: desc,
-	}
+	return resp.envelope(CodeMethodNotAllowed, MsgMethodNotAllowed, desc, nil)
+}
 
-	return resp
+// Conflict build response with HTTP Status 409
+func (resp *ResponseBuilder) Conflict(desc string) *ResponseBuilder {
+	return resp.envelope(CodeConflict, MsgConflict, desc, nil)
 }
 
-func (resp *ResponseBuilder) TooManyRequest(desc string) *ResponseBuilder {
-	resp.statusCode = CodeTooManyRequest
-	resp.body = Response{
-		StatusCode:  CodeTooManyRequest,
-		Status:      MsgTooManyRequest,
-		Description: desc,
-	}
+// Gone build response with HTTP Status 410
+func (resp *ResponseBuilder) Gone(desc string) *ResponseBuilder {
+	return resp.envelope(CodeGone, MsgGone, desc, nil)
+}
 
-	return resp
+// PreconditionFailed build response with HTTP Status 412
+func (resp *ResponseBuilder) PreconditionFailed(desc string) *ResponseBuilder {
+	return resp.envelope(CodePreconditionFailed, MsgPreconditionFailed, desc, nil)
+}
+
+// UnsupportedMediaType build response with HTTP Status 415
+func (resp *ResponseBuilder) UnsupportedMediaType(desc string) *ResponseBuilder {
+	return resp.envelope(CodeUnsupportedMediaType, MsgUnsupportedMediaType, desc, nil)
+}
+
+// UnprocessableEntity build response with HTTP Status 422.
+// details is written as response body, such as validation errors
+func (resp *ResponseBuilder) UnprocessableEntity(desc string, details interface{}) *ResponseBuilder {
+	return resp.envelope(CodeUnprocessableEntity, MsgUnprocessableEntity, desc, details)
+}
+
+// TooManyRequest build response with HTTP Status 429
+func (resp *ResponseBuilder) TooManyRequest(desc string) *ResponseBuilder {
+	return resp.envelope(CodeTooManyRequest, MsgTooManyRequest, desc, nil)
 }
 
 // InternalError build response with HTTP Status 500
 func (resp *ResponseBuilder) InternalError(desc string) *ResponseBuilder {
-	resp.statusCode = CodeInternalError
-	resp.body = Response{
-		StatusCode:  CodeInternalError,
-		Status:      MsgInternalError,
+	return resp.envelope(CodeInternalError, MsgInternalError, desc, nil)
+}
+
+// ServerOverload build response with HTTP Status 503
+func (resp *ResponseBuilder) ServerOverload(desc string) *ResponseBuilder {
+	return resp.envelope(CodeOverload, MsgOverloadError, desc, nil)
+}
+
+// GatewayTimeout build response with HTTP Status 504
+func (resp *ResponseBuilder) GatewayTimeout(desc string) *ResponseBuilder {
+	return resp.envelope(CodeGatewayTimeout, MsgGatewayTimeout, desc, nil)
+}
+
+// Error build response with any HTTP Status code, wrapped in the same envelope as other helpers.
+// details is written as response body, pass nil if there is none
+func (resp *ResponseBuilder) Error(code int, desc string, details interface{}) *ResponseBuilder {
+	return resp.envelope(code, StatusMessage(code), desc, details)
+}
+
+func (resp *ResponseBuilder) envelope(code int, status, desc string, data interface{}) *ResponseBuilder {
+	resp.statusCode = code
+	resp.body = nil
+	resp.response = &Response{
+		StatusCode:  code,
+		Status:      status,
 		Description: desc,
+		Body:        data,
 	}
 
 	return resp
 }
 
-// ServerOverload build response with HTTP Status 503
-func (resp *ResponseBuilder) ServerOverload(desc string) *ResponseBuilder {
-	resp.statusCode = CodeOverload
-	resp.body = Response{
-		StatusCode:  CodeOverload,
-		Status:      MsgOverloadError,
-		Description: desc,
+// ETag set entity tag validator of response, tag is quoted if it is not.
+// Use W/"tag" for weak validator
+func (resp *ResponseBuilder) ETag(tag string) *ResponseBuilder {
+	if tag != "" && !strings.HasSuffix(tag, `"`) {
+		tag = `"` + tag + `"`
 	}
 
+	resp.etag = tag
+
+	return resp
+}
+
+// LastModified set Last-Modified validator of response
+func (resp *ResponseBuilder) LastModified(t time.Time) *ResponseBuilder {
+	resp.lastModified = t
+
 	return resp
 }
 
-func (resp *ResponseBuilder) write(w http.ResponseWriter) {
-	for _, header := range resp.headers {
-		w.Header().Add(header[0], header[1])
+// AutoETag set whether ETag is generated from the encoded body of 2xx response,
+// overriding the mode set by Minirest.AutoETag.
+// ETag set with ResponseBuilder.ETag is never replaced
+func (resp *ResponseBuilder) AutoETag(mode ETagMode) *ResponseBuilder {
+	resp.etagMode = mode
+
+	return resp
+}
+
+// CacheControl set Cache-Control header with directives,
+// for example CacheControl("public", "max-age=60")
+func (resp *ResponseBuilder) CacheControl(directives ...string) *ResponseBuilder {
+	return resp.SetHeader("Cache-Control", strings.Join(directives, ", "))
+}
+
+// MaxAge set Cache-Control header to "max-age" with age in seconds
+func (resp *ResponseBuilder) MaxAge(age time.Duration) *ResponseBuilder {
+	return resp.CacheControl("max-age=" + strconv.Itoa(int(age/time.Second)))
+}
+
+// NoStore set Cache-Control header to "no-store", response is never cached
+func (resp *ResponseBuilder) NoStore() *ResponseBuilder {
+	return resp.CacheControl("no-store")
+}
+
+// Expires set Expires header
+func (resp *ResponseBuilder) Expires(t time.Time) *ResponseBuilder {
+	return resp.SetHeader("Expires", t.UTC().Format(http.TimeFormat))
+}
+
+// Vary add request headers that select the response representation to Vary header
+func (resp *ResponseBuilder) Vary(headers ...string) *ResponseBuilder {
+	return resp.AddHeader("Vary", strings.Join(headers, ", "))
+}
+
+// CacheTags tag response stored by response cache middleware,
+// so it can be removed with ResponseCache.Invalidate
+func (resp *ResponseBuilder) CacheTags(tags ...string) *ResponseBuilder {
+	resp.cacheTags = append(resp.cacheTags, tags...)
+
+	return resp
+}
+
+// hasHeader report whether header key is set by resp
+func (resp *ResponseBuilder) hasHeader(key string) bool {
+	key = http.CanonicalHeaderKey(key)
+	set := false
+	for _, op := range resp.headers {
+		if http.CanonicalHeaderKey(op.key) == key {
+			set = op.op != headerDel
+		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if resp.Gzip {
-		data, err := json.Marshal(resp.body)
-		if err != nil {
-			log.Println(err.Error())
-			return
+	return set
+}
+
+// write write resp into w. Returned error is from encoding or writing body,
+// when headers may have been written already
+func (resp *ResponseBuilder) write(w http.ResponseWriter, r *http.Request, env Envelope) error {
+	// headers set by middlewares are kept unless replaced or deleted by resp
+	header := w.Header()
+	for _, op := range resp.headers {
+		switch op.op {
+		case headerSet:
+			header.Set(op.key, op.value)
+		case headerAdd:
+			header.Add(op.key, op.value)
+		case headerDel:
+			header.Del(op.key)
+		}
+	}
+
+	if tagger, ok := w.(cacheTagger); ok && len(resp.cacheTags) > 0 {
+		tagger.setCacheTags(resp.cacheTags)
+	}
+
+	// 1xx, 204 and 304 responses must not have body nor Content-Type,
+	// description is dropped, see RFC 9110 section 6.4.1
+	if !bodyAllowedForStatus(resp.statusCode) {
+		header.Del("Content-Type")
+		w.WriteHeader(resp.statusCode)
+		return nil
+	}
+
+	contentType, body := "application/json", resp.body
+	if resp.response != nil {
+		if env == nil {
+			env = DefaultEnvelope
+		}
+
+		contentType, body = env.Format(r, *resp.response)
+		if body == nil {
+			w.WriteHeader(resp.statusCode)
+			return nil
+		}
+	}
+
+	if !resp.hasHeader("Content-Type") {
+		header.Set("Content-Type", contentType)
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		w.WriteHeader(CodeInternalError)
+		return err
+	}
+
+	// keep the trailing newline written by json.Encoder
+	if !resp.Gzip {
+		data = append(data, '\n')
+	}
+
+	if resp.etag == "" && resp.statusCode >= 200 && resp.statusCode < 300 {
+		encoded := resp.Gzip || header.Get("Content-Encoding") != ""
+		resp.etag = generateETag(data, resp.etagMode, encoded)
+	}
+
+	if resp.etag != "" {
+		header.Set("ETag", resp.etag)
+	}
+
+	if !resp.lastModified.IsZero() {
+		header.Set("Last-Modified", resp.lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if r != nil && notModified(r, resp.statusCode, resp.etag, resp.lastModified) {
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+		w.WriteHeader(CodeNotModified)
+		return nil
+	}
+
+	// response to HEAD request has the same headers as GET, but without body
+	if r != nil && r.Method == http.MethodHead {
+		if !resp.Gzip && header.Get("Content-Encoding") == "" {
+			header.Set("Content-Length", strconv.Itoa(len(data)))
 		}
 
-		writeGzipResp(w, data, resp.statusCode)
-		return
+		w.WriteHeader(resp.statusCode)
+		return nil
+	}
+
+	if resp.Gzip {
+		return writeGzipResp(w, data, resp.statusCode)
 	}
 
 	w.WriteHeader(resp.statusCode)
-	if err := json.NewEncoder(w).Encode(resp.body); err != nil {
-		log.Println(err.Error())
+	_, err = w.Write(data)
+
+	return err
+}
+
+// bodyAllowedForStatus report whether response with status code can have body
+func bodyAllowedForStatus(code int) bool {
+	switch {
+	case code >= 100 && code < 200:
+		return false
+	case code == CodeNoContent, code == CodeNotModified:
+		return false
 	}
+
+	return true
 }
@@ -0,0 +1,433 @@
+package minirest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestResponseBuilderWrite(t *testing.T) {
+	tests := []struct {
+		name string
+		// header set by middleware before response is written
+		preset  map[string]string
+		resp    *ResponseBuilder
+		env     Envelope
+		status  int
+		headers map[string]string
+		body    string
+	}{
+		{
+			name:    "ok",
+			resp:    new(ResponseBuilder).Ok(map[string]int{"a": 1}),
+			status:  200,
+			headers: map[string]string{"Content-Type": "application/json"},
+			body:    `{"statusCode":200,"status":"ok","body":{"a":1}}` + "\n",
+		},
+		{
+			name:   "nil data is omitted",
+			resp:   new(ResponseBuilder).Ok(nil),
+			status: 200,
+			body:   `{"statusCode":200,"status":"ok"}` + "\n",
+		},
+		{
+			name:   "raw body",
+			resp:   new(ResponseBuilder).Status(202).Body([]string{"x"}),
+			status: 202,
+			body:   `["x"]` + "\n",
+		},
+		{
+			name:   "body replace envelope",
+			resp:   new(ResponseBuilder).Ok("a").Body("b"),
+			status: 200,
+			body:   `"b"` + "\n",
+		},
+		{
+			name:   "envelope replace body",
+			resp:   new(ResponseBuilder).Body("b").BadRequest("invalid"),
+			status: 400,
+			body:   `{"statusCode":400,"status":"bad_request","description":"invalid"}` + "\n",
+		},
+		{
+			name:    "no content drops description and content type",
+			preset:  map[string]string{"Content-Type": "text/plain"},
+			resp:    new(ResponseBuilder).NoContent("deleted"),
+			status:  204,
+			headers: map[string]string{"Content-Type": ""},
+		},
+		{
+			name:    "created with location",
+			resp:    new(ResponseBuilder).Created("/items/1", 1),
+			status:  201,
+			headers: map[string]string{"Location": "/items/1"},
+			body:    `{"statusCode":201,"status":"created","body":1}` + "\n",
+		},
+		{
+			name:    "redirect",
+			resp:    new(ResponseBuilder).Redirect(302, "/login"),
+			status:  302,
+			headers: map[string]string{"Location": "/login"},
+		},
+		{
+			name:   "error with derived status message",
+			resp:   new(ResponseBuilder).Error(418, "short and stout", nil),
+			status: 418,
+			body:   `{"statusCode":418,"status":"im_a_teapot","description":"short and stout"}` + "\n",
+		},
+		{
+			name:   "unprocessable entity with details",
+			resp:   new(ResponseBuilder).UnprocessableEntity("invalid", map[string]string{"name": "required"}),
+			status: 422,
+			body:   `{"statusCode":422,"status":"unprocessable_entity","description":"invalid","body":{"name":"required"}}` + "\n",
+		},
+		{
+			name:    "set header replace middleware header",
+			preset:  map[string]string{"X-Trace": "mw"},
+			resp:    new(ResponseBuilder).Ok(nil).SetHeader("X-Trace", "resp"),
+			status:  200,
+			headers: map[string]string{"X-Trace": "resp"},
+		},
+		{
+			name:    "del header remove middleware header",
+			preset:  map[string]string{"X-Trace": "mw"},
+			resp:    new(ResponseBuilder).Ok(nil).DelHeader("X-Trace"),
+			status:  200,
+			headers: map[string]string{"X-Trace": ""},
+		},
+		{
+			name:    "header ops are applied in order",
+			resp:    new(ResponseBuilder).Ok(nil).DelHeader("X-A").SetHeader("X-A", "1"),
+			status:  200,
+			headers: map[string]string{"X-A": "1"},
+		},
+		{
+			name:    "custom content type",
+			resp:    new(ResponseBuilder).Ok(nil).SetHeader("Content-Type", "application/hal+json"),
+			status:  200,
+			headers: map[string]string{"Content-Type": "application/hal+json"},
+		},
+		{
+			name:   "unencodable body",
+			resp:   new(ResponseBuilder).Ok(make(chan int)),
+			status: 500,
+		},
+		{
+			name:    "raw envelope",
+			resp:    new(ResponseBuilder).Ok([]int{1}),
+			env:     RawEnvelope,
+			status:  200,
+			body:    `[1]` + "\n",
+			headers: map[string]string{"Content-Type": "application/json"},
+		},
+		{
+			name:   "raw envelope write description",
+			resp:   new(ResponseBuilder).NotFound("missing"),
+			env:    RawEnvelope,
+			status: 404,
+			body:   `"missing"` + "\n",
+		},
+		{
+			name:   "raw envelope without data",
+			resp:   new(ResponseBuilder).Ok(nil),
+			env:    RawEnvelope,
+			status: 200,
+		},
+		{
+			name:    "problem envelope",
+			resp:    new(ResponseBuilder).Forbidden("no access"),
+			env:     ProblemEnvelope(nil),
+			status:  403,
+			headers: map[string]string{"Content-Type": "application/problem+json"},
+			body:    `{"type":"about:blank","title":"Forbidden","status":403,"detail":"no access","instance":"/items"}` + "\n",
+		},
+		{
+			name:   "problem envelope success",
+			resp:   new(ResponseBuilder).Ok(1),
+			env:    ProblemEnvelope(nil),
+			status: 200,
+			body:   `{"statusCode":200,"status":"ok","body":1}` + "\n",
+		},
+		{
+			name:    "json api envelope",
+			resp:    new(ResponseBuilder).Ok(map[string]int{"id": 1}),
+			env:     JSONAPIEnvelope,
+			status:  200,
+			headers: map[string]string{"Content-Type": "application/vnd.api+json"},
+			body:    `{"data":{"id":1}}` + "\n",
+		},
+		{
+			name:    "etag is quoted",
+			resp:    new(ResponseBuilder).Ok(nil).ETag("v1"),
+			status:  200,
+			headers: map[string]string{"ETag": `"v1"`},
+		},
+		{
+			name:    "weak etag",
+			resp:    new(ResponseBuilder).Ok(nil).ETag(`W/"v1"`),
+			status:  200,
+			headers: map[string]string{"ETag": `W/"v1"`},
+		},
+		{
+			name:    "auto etag",
+			resp:    new(ResponseBuilder).Ok("a").AutoETag(ETagStrong),
+			status:  200,
+			headers: map[string]string{"ETag": generateETag([]byte(`{"statusCode":200,"status":"ok","body":"a"}`+"\n"), ETagStrong, false)},
+		},
+		{
+			name:    "no auto etag for error",
+			resp:    new(ResponseBuilder).BadRequest("a").AutoETag(ETagStrong),
+			status:  400,
+			headers: map[string]string{"ETag": ""},
+		},
+		{
+			name:    "last modified",
+			resp:    new(ResponseBuilder).Ok(nil).LastModified(time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("X", 3600))),
+			status:  200,
+			headers: map[string]string{"Last-Modified": "Thu, 02 Jan 2020 02:04:05 GMT"},
+		},
+		{
+			name:    "cache control",
+			resp:    new(ResponseBuilder).Ok(nil).MaxAge(90*time.Second).Vary("Accept", "Origin"),
+			status:  200,
+			headers: map[string]string{"Cache-Control": "max-age=90", "Vary": "Accept, Origin"},
+		},
+	}
+
+	silenceLog(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			for key, value := range tt.preset {
+				w.Header().Set(key, value)
+			}
+
+			tt.resp.write(w, httptest.NewRequest("GET", "/items", nil), tt.env)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+
+			for key, want := range tt.headers {
+				if got := w.Header().Get(key); got != want {
+					t.Errorf("header %s = %q, want %q", key, got, want)
+				}
+			}
+
+			if tt.body != "" || tt.status == 204 {
+				if got := w.Body.String(); got != tt.body {
+					t.Errorf("body = %q, want %q", got, tt.body)
+				}
+			}
+		})
+	}
+}
+
+func TestResponseBuilderConditional(t *testing.T) {
+	tests := []struct {
+		name   string
+		header map[string]string
+		status int
+	}{
+		{name: "no validators", status: 200},
+		{name: "if-none-match match", header: map[string]string{"If-None-Match": `"v1"`}, status: 304},
+		{name: "if-none-match weak comparison", header: map[string]string{"If-None-Match": `W/"v1"`}, status: 304},
+		{name: "if-none-match list", header: map[string]string{"If-None-Match": `"v0", "v1"`}, status: 304},
+		{name: "if-none-match any", header: map[string]string{"If-None-Match": `*`}, status: 304},
+		{name: "if-none-match mismatch", header: map[string]string{"If-None-Match": `"v2"`}, status: 200},
+		{name: "if-modified-since not modified", header: map[string]string{"If-Modified-Since": "Thu, 02 Jan 2020 00:00:00 GMT"}, status: 304},
+		{name: "if-modified-since modified", header: map[string]string{"If-Modified-Since": "Wed, 01 Jan 2020 00:00:00 GMT"}, status: 200},
+		{
+			name:   "if-none-match take precedence",
+			header: map[string]string{"If-None-Match": `"v2"`, "If-Modified-Since": "Thu, 02 Jan 2020 00:00:00 GMT"},
+			status: 200,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			for key, value := range tt.header {
+				r.Header.Set(key, value)
+			}
+
+			w := httptest.NewRecorder()
+			resp := new(ResponseBuilder).Ok("a").ETag("v1").LastModified(time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC))
+			resp.write(w, r, nil)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+
+			if tt.status == 304 && (w.Body.Len() != 0 || w.Header().Get("Content-Type") != "") {
+				t.Errorf("304 has body %q or Content-Type %q", w.Body, w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestResponseBuilderCookies(t *testing.T) {
+	insecure := &http.Cookie{Name: "a", Value: "1"}
+	w := httptest.NewRecorder()
+	new(ResponseBuilder).Ok(nil).
+		SetCookie("session", "s").
+		AddCookie(insecure).
+		ClearCookie("old").
+		write(w, nil, nil)
+
+	// cookie is encoded when added
+	insecure.Value = "2"
+
+	got := w.Header()["Set-Cookie"]
+	want := []string{
+		"session=s; Path=/; HttpOnly; Secure; SameSite=Lax",
+		"a=1",
+		"old=; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 GMT; Max-Age=0; HttpOnly; Secure; SameSite=Lax",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Set-Cookie = %q,\nwant %q", got, want)
+	}
+
+	if insecure.Path != "" || insecure.HttpOnly || insecure.Secure || insecure.SameSite != 0 {
+		t.Errorf("cookie is modified: %+v", insecure)
+	}
+}
+
+func TestResponseBuilderMiddlewareHeaders(t *testing.T) {
+	// middleware set headers before and after controller builds its response
+	middleware := func(next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, pathVars httprouter.Params) {
+			http.SetCookie(w, &http.Cookie{Name: "mw", Value: "1"})
+			w.Header().Set("X-Trace", "mw")
+			w.Header().Set("X-Frame-Options", "DENY")
+			w.Header().Add("Vary", "Origin")
+			next(w, r, pathVars)
+		}
+	}
+
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.GET("/login", func() *ResponseBuilder {
+			return new(ResponseBuilder).Ok(nil).
+				SetCookie("session", "s").
+				SetHeader("X-Trace", "controller").
+				AddHeader("Vary", "Accept").
+				DelHeader("X-Frame-Options")
+		}, middleware)
+	})
+
+	w := serve(mn, "GET", "/login", nil)
+	want := http.Header{
+		"Set-Cookie": {"mw=1", "session=s; Path=/; HttpOnly; Secure; SameSite=Lax"},
+		"X-Trace":    {"controller"},
+		"Vary":       {"Origin", "Accept"},
+	}
+
+	for key, values := range want {
+		if got := w.Header()[key]; !reflect.DeepEqual(got, values) {
+			t.Errorf("%s = %q, want %q", key, got, values)
+		}
+	}
+
+	if _, ok := w.Header()["X-Frame-Options"]; ok {
+		t.Error("X-Frame-Options is not deleted")
+	}
+}
+
+func TestResponseBuilderHeaders(t *testing.T) {
+	w := httptest.NewRecorder()
+	new(ResponseBuilder).Ok(nil).
+		Headers([][2]string{{"X-A", "1"}, {"X-A", "2"}}).
+		Headers([][2]string{{"X-B", "3"}}).
+		write(w, nil, nil)
+
+	if got := strings.Join(w.Header()["X-A"], ","); got != "1,2" {
+		t.Errorf("X-A = %q, want 1,2", got)
+	}
+
+	if got := w.Header().Get("X-B"); got != "3" {
+		t.Errorf("X-B = %q, want 3", got)
+	}
+}
+
+func TestStatusMessage(t *testing.T) {
+	tests := map[int]string{
+		200: MsgOk,
+		404: MsgNotFound,
+		429: MsgTooManyRequest,
+		503: MsgOverloadError,
+		418: "im_a_teapot",
+		511: "network_authentication_required",
+		299: "unknown",
+	}
+
+	for code, want := range tests {
+		if got := StatusMessage(code); got != want {
+			t.Errorf("StatusMessage(%d) = %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestBodyAllowedForStatus(t *testing.T) {
+	for code, want := range map[int]bool{100: false, 101: false, 200: true, 204: false, 205: true, 304: false, 404: true} {
+		if got := bodyAllowedForStatus(code); got != want {
+			t.Errorf("bodyAllowedForStatus(%d) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestResponseBuilderBodiless(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		resp   *ResponseBuilder
+		status int
+	}{
+		{name: "no content", method: "GET", resp: new(ResponseBuilder).NoContent("deleted"), status: 204},
+		{name: "no content with raw body", method: "GET", resp: new(ResponseBuilder).Status(204).Body("x"), status: 204},
+		{name: "not modified", method: "GET", resp: new(ResponseBuilder).NotModified(), status: 304},
+		{name: "gzip no content", method: "GET", resp: (&ResponseBuilder{Gzip: true}).NoContent(""), status: 204},
+		{name: "head", method: "HEAD", resp: new(ResponseBuilder).Ok("a"), status: 200},
+		{name: "head error", method: "HEAD", resp: new(ResponseBuilder).NotFound("missing"), status: 404},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// served by net/http, which reject body written for bodiless response
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := tt.resp.write(w, r, nil); err != nil {
+					t.Errorf("write: %v", err)
+				}
+			}))
+			defer srv.Close()
+
+			req, _ := http.NewRequest(tt.method, srv.URL, nil)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.status || len(body) != 0 {
+				t.Errorf("status = %d, body %q", resp.StatusCode, body)
+			}
+
+			if tt.method != "HEAD" && resp.Header.Get("Content-Type") != "" {
+				t.Errorf("Content-Type = %q", resp.Header.Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestResponseBuilderInformational(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set("Content-Type", "application/json")
+	new(ResponseBuilder).Status(http.StatusEarlyHints).Body("x").write(w, httptest.NewRequest("GET", "/", nil), nil)
+	if w.Body.Len() != 0 || w.Header().Get("Content-Type") != "" {
+		t.Errorf("body = %q, Content-Type %q", w.Body, w.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,194 @@
+package minirest
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// RoutesEnv is environment variable that make RunServer print route table
+// of the app to stdout and return, instead of serving it
+const RoutesEnv = "MINIREST_ROUTES"
+
+// Route kinds
+const (
+	RouteREST      = "rest"
+	RouteSSE       = "sse"
+	RouteWebSocket = "websocket"
+	RouteHandler   = "handler"
+	RouteMount     = "mount"
+)
+
+// Binding sources
+const (
+	BindPath  = "path"
+	BindQuery = "query"
+	BindBody  = "body"
+	// Deprecated: callback parameters that can't be bound panic at registration,
+	// so BindNone never appear in RouteInfo.Bindings
+	BindNone = "none"
+)
+
+// RouteInfo describe registered route
+type RouteInfo struct {
+	Method string
+	// Path is full path registered in router, including base paths and version prefix
+	Path string
+	Kind string
+	// Controller is type name of controller, empty for Handler and Mount
+	Controller string
+	// Handler is name of callback, such as "Get" for method value ctrl.Get
+	Handler string
+	// Middlewares is names of middlewares, in execution order
+	Middlewares []string
+	Version     string
+	// Bindings describe how callback parameters are bound from request, in parameter order
+	Bindings []Binding
+	// Doc is documentation set by Endpoints.Doc
+	Doc *EndpointDoc
+}
+
+// Binding describe how a callback parameter is bound from request
+type Binding struct {
+	// Source is one of BindPath, BindQuery and BindBody
+	Source string
+	// Name is path variable name, or query name of typed handler field, empty for other sources
+	Name string
+	// Type is Go type of parameter
+	Type     string
+	typ      reflect.Type
+	required bool
+}
+
+// Routes return all registered routes, in registration order
+func (mn *Minirest) Routes() []RouteInfo {
+	routes := make([]RouteInfo, len(mn.routes))
+	copy(routes, mn.routes)
+
+	return routes
+}
+
+// PrintRoutes write route table sorted by path and method into w
+func (mn *Minirest) PrintRoutes(w io.Writer) {
+	routes := mn.Routes()
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+
+		return routes[i].Method < routes[j].Method
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATH\tVERSION\tHANDLER\tMIDDLEWARES")
+	for _, route := range routes {
+		handler := route.Handler
+		if route.Controller != "" {
+			handler = route.Controller + "." + handler
+		}
+
+		if handler == "" {
+			handler = "(" + route.Kind + ")"
+		}
+
+		version := route.Version
+		if version == "" {
+			version = "-"
+		}
+
+		mds := strings.Join(route.Middlewares, ", ")
+		if mds == "" {
+			mds = "-"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", route.Method, route.Path, version, handler, mds)
+	}
+
+	tw.Flush()
+}
+
+func (mn *Minirest) addRoute(info RouteInfo, path string) {
+	info.Path = path
+	mn.routes = append(mn.routes, info)
+}
+
+// conflicts describe registered routes that may conflict with method and path
+func (mn *Minirest) conflicts(method, path string) string {
+	var sb strings.Builder
+	segments := strings.Split(path, "/")
+	for _, route := range mn.routes {
+		if route.Method != method {
+			continue
+		}
+
+		other := strings.Split(route.Path, "/")
+		n := len(segments)
+		if len(other) < n {
+			n = len(other)
+		}
+
+		for i := 0; i < n; i++ {
+			a, b := segments[i], other[i]
+			if isPathVar(a) || isPathVar(b) {
+				if a != b {
+					sb.WriteString("\n\t" + route.Method + " " + route.Path + " (" + route.Controller + "." + route.Handler + ")")
+				}
+
+				break
+			}
+
+			if a != b {
+				break
+			}
+		}
+	}
+
+	if sb.Len() == 0 {
+		return ""
+	}
+
+	return "\nregistered routes that may conflict:" + sb.String()
+}
+
+func isPathVar(segment string) bool {
+	return strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*")
+}
+
+// pathVars return names of path variables in path
+func pathVars(path string) []string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if isPathVar(segment) {
+			names = append(names, segment[1:])
+		}
+	}
+
+	return names
+}
+
+// funcName return short name of function, such as "Get" for method value
+// or "Middleware1" for function
+func funcName(fn interface{}) string {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		return ""
+	}
+
+	f := runtime.FuncForPC(v.Pointer())
+	if f == nil {
+		return ""
+	}
+
+	name := strings.TrimSuffix(f.Name(), "-fm")
+	name = name[strings.LastIndex(name, "/")+1:]
+	// method value, such as pkg.(*Controller).Get
+	if i := strings.LastIndex(name, ")."); i != -1 {
+		return name[i+2:]
+	}
+
+	return name
+}
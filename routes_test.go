@@ -0,0 +1,151 @@
+package minirest
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type routesController struct{}
+
+func (ctrl *routesController) Endpoints() *Endpoints {
+	ep := new(Endpoints)
+	ep.BasePath("/users")
+	ep.Middlewares(requireAuth)
+	ep.GET("/:id", ctrl.Get, logRequest)
+	ep.GET("", ctrl.List)
+	ep.POST("", ctrl.Create)
+
+	v2 := ep.Group("")
+	v2.Version("v2")
+	v2.DELETE("/:id", ctrl.Delete)
+
+	return ep
+}
+
+func (ctrl *routesController) Get(id int) *ResponseBuilder            { return echo(id) }
+func (ctrl *routesController) List(query testQuery) *ResponseBuilder  { return echo(query) }
+func (ctrl *routesController) Create(body *testBody) *ResponseBuilder { return echo(body) }
+func (ctrl *routesController) Delete(id int) *ResponseBuilder         { return echo(id) }
+
+func requireAuth(next httprouter.Handle) httprouter.Handle { return next }
+func logRequest(next httprouter.Handle) httprouter.Handle  { return next }
+
+func TestRoutes(t *testing.T) {
+	mn := New()
+	mn.AddController(new(routesController))
+	mn.Mount("/static", nil)
+
+	routes := mn.Routes()
+	want := []RouteInfo{
+		{
+			Method: "GET", Path: "/users/:id", Kind: RouteREST, Controller: "routesController", Handler: "Get",
+			Middlewares: []string{"minirest.requireAuth", "minirest.logRequest"},
+			Bindings:    []Binding{{Source: BindPath, Name: "id", Type: "int"}},
+		},
+		{
+			Method: "GET", Path: "/users", Kind: RouteREST, Controller: "routesController", Handler: "List",
+			Middlewares: []string{"minirest.requireAuth"},
+			Bindings:    []Binding{{Source: BindQuery, Type: "minirest.testQuery"}},
+		},
+		{
+			Method: "POST", Path: "/users", Kind: RouteREST, Controller: "routesController", Handler: "Create",
+			Middlewares: []string{"minirest.requireAuth"},
+			Bindings:    []Binding{{Source: BindBody, Type: "*minirest.testBody"}},
+		},
+		{
+			Method: "DELETE", Path: "/v2/users/:id", Kind: RouteREST, Controller: "routesController", Handler: "Delete",
+			Middlewares: []string{"minirest.requireAuth"}, Version: "v2",
+			Bindings: []Binding{{Source: BindPath, Name: "id", Type: "int"}},
+		},
+	}
+
+	if len(routes) != len(want)+len(methodOrder)+1 {
+		t.Fatalf("got %d routes", len(routes))
+	}
+
+	for i, route := range routes[:len(want)] {
+		for j := range route.Bindings {
+			route.Bindings[j].typ = nil
+		}
+
+		if !reflect.DeepEqual(route, want[i]) {
+			t.Errorf("route %d = %+v,\nwant %+v", i, route, want[i])
+		}
+	}
+
+	if mount := routes[len(want)]; mount.Kind != RouteMount || mount.Path != "/static/*mountpath" {
+		t.Errorf("mount route = %+v", mount)
+	}
+
+	// returned routes is a copy
+	routes[0].Path = "/changed"
+	if mn.Routes()[0].Path != "/users/:id" {
+		t.Error("Routes return internal slice")
+	}
+}
+
+func TestPrintRoutes(t *testing.T) {
+	mn := New()
+	mn.AddController(new(routesController))
+
+	var buf bytes.Buffer
+	mn.PrintRoutes(&buf)
+	want := strings.Join([]string{
+		"METHOD  PATH           VERSION  HANDLER                  MIDDLEWARES",
+		"GET     /users         -        routesController.List    minirest.requireAuth",
+		"POST    /users         -        routesController.Create  minirest.requireAuth",
+		"GET     /users/:id     -        routesController.Get     minirest.requireAuth, minirest.logRequest",
+		"DELETE  /v2/users/:id  v2       routesController.Delete  minirest.requireAuth",
+	}, "\n") + "\n"
+
+	if buf.String() != want {
+		t.Errorf("PrintRoutes =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestRouteConflict(t *testing.T) {
+	defer func() {
+		msg := fmt.Sprint(recover())
+		for _, want := range []string{
+			"minirest: can not register GET /users/me",
+			"registered routes that may conflict:",
+			"GET /users/:id (routesController.Get)",
+		} {
+			if !strings.Contains(msg, want) {
+				t.Errorf("panic %q doesn't contain %q", msg, want)
+			}
+		}
+
+		if strings.Contains(msg, "DELETE") {
+			t.Errorf("panic %q list route of other method", msg)
+		}
+	}()
+
+	mn := New()
+	mn.AddController(new(routesController))
+	mn.AddController(&testController{method: "GET", path: "/users/me", callback: func() *ResponseBuilder { return echo(1) }})
+}
+
+func TestFuncName(t *testing.T) {
+	ctrl := new(routesController)
+	tests := []struct {
+		fn   interface{}
+		want string
+	}{
+		{fn: ctrl.Get, want: "Get"},
+		{fn: (*routesController).List, want: "List"},
+		{fn: requireAuth, want: "minirest.requireAuth"},
+		{fn: 1, want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := funcName(tt.fn); got != tt.want {
+			t.Errorf("funcName = %q, want %q", got, tt.want)
+		}
+	}
+}
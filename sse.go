@@ -0,0 +1,240 @@
+package minirest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// default interval for SSE heartbeat comment
+const defaultSSEHeartbeat = 15 * time.Second
+
+// ErrStreamClosed returned when sending event to closed EventStream
+var ErrStreamClosed = errors.New("event stream is closed")
+
+// Event is a single Server-Sent Event
+type Event struct {
+	// ID is event id. Client will send the last received id
+	// through Last-Event-ID header when reconnecting
+	ID string
+	// Event is event type, client will receive it as "message" if empty
+	Event string
+	// Data is event payload. string and []byte are sent as is,
+	// other types are encoded to JSON. nil is sent as empty data,
+	// so the event is still dispatched by client
+	Data interface{}
+	// Retry tell client how long to wait before reconnecting
+	Retry time.Duration
+}
+
+// EventStream is event sink for SSE endpoint.
+// EventStream is safe to use from multiple goroutines
+type EventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	r       *http.Request
+	params  httprouter.Params
+	mu      sync.Mutex
+	closed  bool
+	lastID  string
+}
+
+// Send send event to client.
+// Send returns error if client is disconnected or stream is closed
+func (es *EventStream) Send(ev Event) error {
+	var sb strings.Builder
+	if ev.ID != "" {
+		sb.WriteString("id: " + stripNewline(ev.ID) + "\n")
+	}
+
+	if ev.Event != "" {
+		sb.WriteString("event: " + stripNewline(ev.Event) + "\n")
+	}
+
+	if ev.Retry > 0 {
+		sb.WriteString("retry: " + strconv.FormatInt(int64(ev.Retry/time.Millisecond), 10) + "\n")
+	}
+
+	var data string
+	switch v := ev.Data.(type) {
+	case nil:
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		data = string(raw)
+	}
+
+	for _, line := range strings.Split(data, "\n") {
+		sb.WriteString("data: " + strings.TrimSuffix(line, "\r") + "\n")
+	}
+
+	sb.WriteString("\n")
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if err := es.writeLocked(sb.String()); err != nil {
+		return err
+	}
+
+	if ev.ID != "" {
+		es.lastID = ev.ID
+	}
+
+	return nil
+}
+
+// Data send event with payload data only
+func (es *EventStream) Data(data interface{}) error {
+	return es.Send(Event{Data: data})
+}
+
+// Comment send comment line, ignored by client
+func (es *EventStream) Comment(text string) error {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	return es.writeLocked(": " + stripNewline(text) + "\n\n")
+}
+
+// LastEventID return id of the last event received by client.
+// Before any event is sent it return the value of Last-Event-ID header,
+// so callback can resume the stream from there
+func (es *EventStream) LastEventID() string {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	return es.lastID
+}
+
+// Request return the underlying request
+func (es *EventStream) Request() *http.Request {
+	return es.r
+}
+
+// Params return path variables
+func (es *EventStream) Params() httprouter.Params {
+	return es.params
+}
+
+// Context return request context, canceled when client is disconnected
+// or server is shut down
+func (es *EventStream) Context() context.Context {
+	return es.r.Context()
+}
+
+// Done return channel that closed when client is disconnected or server is shut down
+func (es *EventStream) Done() <-chan struct{} {
+	return es.r.Context().Done()
+}
+
+func (es *EventStream) writeLocked(data string) error {
+	if es.closed {
+		return ErrStreamClosed
+	}
+
+	if err := es.r.Context().Err(); err != nil {
+		return err
+	}
+
+	if _, err := es.w.Write([]byte(data)); err != nil {
+		return err
+	}
+
+	es.flusher.Flush()
+	return nil
+}
+
+func (es *EventStream) close() {
+	es.mu.Lock()
+	es.closed = true
+	es.mu.Unlock()
+}
+
+func (es *EventStream) heartbeat(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-es.Done():
+			return
+		case <-ticker.C:
+			if err := es.Comment("heartbeat"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func stripNewline(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// wrapper for SSE endpoint. Stream is kept open until callback returns
+// or the client is disconnected
+func (mn *Minirest) handleSSE(callback func(*EventStream), heartbeat time.Duration) httprouter.Handle {
+	if heartbeat == 0 {
+		heartbeat = defaultSSEHeartbeat
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, pathVars httprouter.Params) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writer := new(ResponseBuilder)
+			writer.InternalError("streaming is not supported")
+			mn.writeResponse(w, r, writer)
+			return
+		}
+
+		header := w.Header()
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("Connection", "keep-alive")
+		header.Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		// stream is ended when client is disconnected or server is shut down
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		go func() {
+			select {
+			case <-mn.shutdown:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		stream := &EventStream{
+			w:       w,
+			flusher: flusher,
+			r:       r.WithContext(ctx),
+			params:  pathVars,
+			lastID:  r.Header.Get("Last-Event-ID"),
+		}
+
+		done := make(chan struct{})
+		if heartbeat > 0 {
+			go stream.heartbeat(heartbeat, done)
+		}
+
+		callback(stream)
+		close(done)
+		stream.close()
+	}
+}
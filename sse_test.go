@@ -0,0 +1,192 @@
+package minirest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// noFlushWriter is http.ResponseWriter without http.Flusher
+type noFlushWriter struct {
+	http.ResponseWriter
+}
+
+func TestSSEFraming(t *testing.T) {
+	tests := []struct {
+		name string
+		ev   Event
+		want string
+	}{
+		{name: "data", ev: Event{Data: "hello"}, want: "data: hello\n\n"},
+		{name: "multi line", ev: Event{Data: "a\r\nb\nc"}, want: "data: a\ndata: b\ndata: c\n\n"},
+		{name: "bytes", ev: Event{Data: []byte("raw")}, want: "data: raw\n\n"},
+		{name: "json", ev: Event{Data: map[string]int{"n": 1}}, want: "data: {\"n\":1}\n\n"},
+		{
+			name: "all fields",
+			ev:   Event{ID: "7", Event: "update", Retry: 3 * time.Second, Data: "x"},
+			want: "id: 7\nevent: update\nretry: 3000\ndata: x\n\n",
+		},
+		{name: "newline in id and event", ev: Event{ID: "1\n2", Event: "a\r\nb", Data: "x"}, want: "id: 12\nevent: ab\ndata: x\n\n"},
+		// empty data line is sent, so the event is still dispatched by client
+		{name: "nil data", ev: Event{Event: "ping"}, want: "event: ping\ndata: \n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mn := newEndpointsApp(func(ep *Endpoints) {
+				ep.SSEHeartbeat(-1)
+				ep.SSE("/events", func(stream *EventStream) {
+					if err := stream.Send(tt.ev); err != nil {
+						t.Errorf("Send: %v", err)
+					}
+				})
+			})
+
+			w := serve(mn, "GET", "/events", nil)
+			if w.Body.String() != tt.want {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestSSEHeaders(t *testing.T) {
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.SSE("/events", func(stream *EventStream) {
+			stream.Comment("hi\nthere")
+		})
+	})
+
+	w := serve(mn, "GET", "/events", nil)
+	want := map[string]string{
+		"Content-Type":      "text/event-stream",
+		"Cache-Control":     "no-cache",
+		"X-Accel-Buffering": "no",
+	}
+
+	for key, value := range want {
+		if got := w.Header().Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+
+	if w.Code != http.StatusOK || w.Body.String() != ": hithere\n\n" || !w.Flushed {
+		t.Errorf("status = %d, body %q, flushed %v", w.Code, w.Body.String(), w.Flushed)
+	}
+}
+
+func TestSSELastEventID(t *testing.T) {
+	var before, after string
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.SSE("/events/:topic", func(stream *EventStream) {
+			before = stream.LastEventID()
+			stream.Send(Event{ID: "11", Data: stream.Params().ByName("topic")})
+			stream.Data("no id")
+			after = stream.LastEventID()
+		})
+	})
+
+	r := httptest.NewRequest("GET", "/events/news", nil)
+	r.Header.Set("Last-Event-ID", "10")
+	w := httptest.NewRecorder()
+	mn.ServeHTTP(w, r)
+	if before != "10" || after != "11" {
+		t.Errorf("LastEventID before = %q, after = %q", before, after)
+	}
+
+	if !strings.HasPrefix(w.Body.String(), "id: 11\ndata: news\n\n") {
+		t.Errorf("body = %q", w.Body.String())
+	}
+}
+
+func TestSSEHeartbeat(t *testing.T) {
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.SSEHeartbeat(5 * time.Millisecond)
+		ep.SSE("/events", func(stream *EventStream) {
+			time.Sleep(30 * time.Millisecond)
+		})
+	})
+
+	w := serve(mn, "GET", "/events", nil)
+	if !strings.HasPrefix(w.Body.String(), ": heartbeat\n\n") {
+		t.Errorf("body = %q", w.Body.String())
+	}
+}
+
+func TestSSEClosed(t *testing.T) {
+	var stream *EventStream
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.SSE("/events", func(es *EventStream) {
+			stream = es
+		})
+	})
+
+	serve(mn, "GET", "/events", nil)
+	if err := stream.Data("late"); err != ErrStreamClosed {
+		t.Errorf("Send after callback returned = %v, want ErrStreamClosed", err)
+	}
+}
+
+func TestSSEDisconnect(t *testing.T) {
+	errc := make(chan error, 1)
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.SSE("/events", func(stream *EventStream) {
+			<-stream.Done()
+			errc <- stream.Data("gone")
+		})
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest("GET", "/events", nil).WithContext(ctx)
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		cancel()
+	}()
+
+	mn.ServeHTTP(httptest.NewRecorder(), r)
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("Send after disconnect = %v", err)
+	}
+}
+
+func TestSSEShutdown(t *testing.T) {
+	started := make(chan struct{})
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.SSE("/events", func(stream *EventStream) {
+			close(started)
+			<-stream.Done()
+		})
+	})
+
+	done := make(chan struct{})
+	go func() {
+		serve(mn, "GET", "/events", nil)
+		close(done)
+	}()
+
+	<-started
+	mn.Shutdown(context.Background())
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stream is not ended by Shutdown")
+	}
+}
+
+func TestSSEWithoutFlusher(t *testing.T) {
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.SSE("/events", func(stream *EventStream) {
+			t.Error("callback is called")
+		})
+	})
+
+	w := httptest.NewRecorder()
+	mn.ServeHTTP(noFlushWriter{w}, httptest.NewRequest("GET", "/events", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d", w.Code)
+	}
+}
@@ -0,0 +1,149 @@
+package minirest
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type requestKey struct{}
+
+// HTTPError is error returned by typed handler to write error response with status Code.
+// Other errors are written as 500 Internal Server Error
+type HTTPError struct {
+	Code        int
+	Description string
+	Details     interface{}
+}
+
+// NewHTTPError create HTTPError with status code and description
+func NewHTTPError(code int, desc string) *HTTPError {
+	return &HTTPError{Code: code, Description: desc}
+}
+
+// Error return description of e
+func (e *HTTPError) Error() string {
+	return e.Description
+}
+
+// typedEndpoint is endpoint added by Handle
+type typedEndpoint interface {
+	callback() interface{}
+	request() reflect.Type
+	response() reflect.Type
+	handler(mn *Minirest, binder *requestBinder) httprouter.Handle
+}
+
+type typedHandler[Req, Resp any] struct {
+	fn func(ctx context.Context, req Req) (Resp, error)
+}
+
+// Handle add endpoint with typed handler fn into ep, as alternative to callback.
+// Request is bound into Req, see below, and Resp is written as body of 200 OK response,
+// unless Resp is *ResponseBuilder which is written as is, or as 204 No Content if it's nil.
+// Returned *HTTPError is written with its code, and other errors are written as 500 Internal Server Error.
+//
+// Fields of Req tagged with path are bound from path variables, fields tagged with query
+// are bound from url queries, and for POST, PUT and PATCH the rest of Req is decoded from JSON body.
+// Example:
+//
+//	type UpdateUser struct {
+//		ID     int    `path:"id"`
+//		Notify bool   `query:"notify"`
+//		Name   string `json:"name"`
+//	}
+//
+//	minirest.Handle(ep, "PUT", "/users/:id", func(ctx context.Context, req UpdateUser) (User, error) {
+//		...
+//	})
+//
+// Request is available from ctx by RequestFromContext. mds is middlewares only applied to this endpoint
+func Handle[Req, Resp any](ep *Endpoints, method, path string, fn func(ctx context.Context, req Req) (Resp, error), mds ...handleToHandle) {
+	ep.add(method, path, &typedHandler[Req, Resp]{fn: fn}, kindTyped, mds)
+}
+
+// RequestFromContext return request of typed handler from its ctx
+func RequestFromContext(ctx context.Context) *http.Request {
+	r, _ := ctx.Value(requestKey{}).(*http.Request)
+	return r
+}
+
+func (h *typedHandler[Req, Resp]) callback() interface{} {
+	return h.fn
+}
+
+func (h *typedHandler[Req, Resp]) request() reflect.Type {
+	return reflect.TypeOf((*Req)(nil)).Elem()
+}
+
+func (h *typedHandler[Req, Resp]) response() reflect.Type {
+	return reflect.TypeOf((*Resp)(nil)).Elem()
+}
+
+func (h *typedHandler[Req, Resp]) handler(mn *Minirest, binder *requestBinder) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, pathVars httprouter.Params) {
+		var req Req
+		if err := binder.bind(reflect.ValueOf(&req).Elem(), r, pathVars); err != nil {
+			markBindFailure(w)
+			mn.writeResponse(w, r, new(ResponseBuilder).BadRequest(err.Error()))
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), httprouter.ParamsKey, pathVars)
+		ctx = context.WithValue(ctx, requestKey{}, r)
+		resp, err := h.fn(ctx, req)
+		if err != nil {
+			mn.writeResponse(w, r, mn.errorResponse(r, err))
+			return
+		}
+
+		if builder, ok := interface{}(resp).(*ResponseBuilder); ok {
+			if builder == nil {
+				builder = new(ResponseBuilder).NoContent("")
+			}
+
+			mn.writeResponse(w, r, builder)
+			return
+		}
+
+		mn.writeResponse(w, r, new(ResponseBuilder).Ok(resp))
+	}
+}
+
+func (mn *Minirest) errorResponse(r *http.Request, err error) *ResponseBuilder {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return new(ResponseBuilder).Error(httpErr.Code, httpErr.Description, httpErr.Details)
+	}
+
+	mn.logRequest(r, slog.LevelError, "minirest: handler failed", "error", err)
+	return new(ResponseBuilder).InternalError("internal server error")
+}
+
+// addTyped set handler of typed endpoint, and describe its request and response in info
+func (mn *Minirest) addTyped(endpoint endpoint, info *RouteInfo) httprouter.Handle {
+	typed := endpoint.callback.(typedEndpoint)
+	method := strings.ToLower(endpoint.method)
+	binder := newRequestBinder(typed.request(), info.Path, method == "post" || method == "put" || method == "patch")
+	info.Handler = funcName(typed.callback())
+	info.Bindings = binder.bindings()
+
+	// response is documented from Resp, unless it's set by EndpointDoc
+	resp := typed.response()
+	if resp != reflect.TypeOf((*ResponseBuilder)(nil)) && (info.Doc == nil || info.Doc.Response == nil) {
+		doc := EndpointDoc{}
+		if info.Doc != nil {
+			doc = *info.Doc
+		}
+
+		doc.Response = reflect.Zero(resp).Interface()
+		info.Doc = &doc
+	}
+
+	return typed.handler(mn, binder)
+}
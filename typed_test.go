@@ -0,0 +1,334 @@
+package minirest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type updateUser struct {
+	ID      int        `path:"id"`
+	Notify  bool       `query:"notify"`
+	Tags    []string   `query:"tag"`
+	Since   *time.Time `query:"since"`
+	Name    string     `json:"name"`
+	Age     int        `json:"age"`
+	private string
+}
+
+type listUsers struct {
+	Group string `path:"group"`
+	Page  int    `query:"page,required"`
+	Limit *uint8 `query:"limit"`
+}
+
+func TestHandleBinding(t *testing.T) {
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		Handle(ep, "PUT", "/users/:id", func(ctx context.Context, req updateUser) (updateUser, error) {
+			return req, nil
+		})
+		Handle(ep, "GET", "/groups/:group/users", func(ctx context.Context, req listUsers) (map[string]interface{}, error) {
+			resp := map[string]interface{}{"group": req.Group, "page": req.Page}
+			if req.Limit != nil {
+				resp["limit"] = *req.Limit
+			}
+
+			return resp, nil
+		})
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		code   int
+		want   string
+	}{
+		{
+			name:   "path, query and body",
+			method: "PUT",
+			target: "/users/7?notify=true&tag=a&tag=b&since=2020-01-02T03:04:05Z",
+			body:   `{"name":"John","age":20}`,
+			code:   200,
+			want:   `{"ID":7,"Notify":true,"Tags":["a","b"],"Since":"2020-01-02T03:04:05Z","name":"John","age":20}`,
+		},
+		{
+			name:   "path takes precedence over body",
+			method: "PUT",
+			target: "/users/7",
+			body:   `{"ID":9,"name":"John"}`,
+			code:   200,
+			want:   `{"ID":7,"Notify":false,"Tags":null,"Since":null,"name":"John","age":0}`,
+		},
+		{
+			name:   "body can't set path and query fields",
+			method: "PUT",
+			target: "/users/7",
+			body:   `{"ID":9,"Notify":true,"Tags":["admin"],"Since":"2020-01-02T03:04:05Z","name":"John"}`,
+			code:   200,
+			want:   `{"ID":7,"Notify":false,"Tags":null,"Since":null,"name":"John","age":0}`,
+		},
+		{
+			name:   "empty body",
+			method: "PUT",
+			target: "/users/7",
+			code:   200,
+			want:   `{"ID":7,"Notify":false,"Tags":null,"Since":null,"name":"","age":0}`,
+		},
+		{
+			name:   "invalid path variable",
+			method: "PUT",
+			target: "/users/x",
+			code:   400,
+			want:   `"id is not type int"`,
+		},
+		{
+			name:   "invalid query",
+			method: "PUT",
+			target: "/users/7?notify=maybe",
+			code:   400,
+			want:   `"notify is not type bool"`,
+		},
+		{
+			name:   "invalid body",
+			method: "PUT",
+			target: "/users/7",
+			body:   `{"name":1}`,
+			code:   400,
+		},
+		{
+			name:   "without body",
+			method: "GET",
+			target: "/groups/admin/users?page=2&limit=10",
+			code:   200,
+			want:   `{"group":"admin","limit":10,"page":2}`,
+		},
+		{
+			name:   "required query",
+			method: "GET",
+			target: "/groups/admin/users",
+			code:   400,
+			want:   `"page is required"`,
+		},
+		{
+			name:   "overflow",
+			method: "GET",
+			target: "/groups/admin/users?page=1&limit=300",
+			code:   400,
+			want:   `"limit is not type uint8"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(mn, tt.method, tt.target, strings.NewReader(tt.body))
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.code, w.Body.String())
+			}
+
+			if tt.want == "" {
+				return
+			}
+
+			var resp struct {
+				Body        json.RawMessage `json:"body"`
+				Description string          `json:"description"`
+			}
+
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+
+			got := string(resp.Body)
+			if tt.code != 200 {
+				raw, _ := json.Marshal(resp.Description)
+				got = string(raw)
+			}
+
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleErrors(t *testing.T) {
+	silenceLog(t)
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		Handle(ep, "GET", "/items/:id", func(ctx context.Context, req struct {
+			ID string `path:"id"`
+		}) (*ResponseBuilder, error) {
+			switch req.ID {
+			case "missing":
+				return nil, NewHTTPError(http.StatusNotFound, "item not found")
+			case "failed":
+				return nil, errors.New("database is down")
+			case "conflict":
+				return nil, wrapErr{&HTTPError{Code: http.StatusConflict, Description: "conflict", Details: req.ID}}
+			case "empty":
+				return nil, nil
+			}
+
+			return new(ResponseBuilder).Created("/items/"+req.ID, req.ID), nil
+		})
+	})
+
+	tests := []struct {
+		target string
+		code   int
+		desc   string
+	}{
+		{target: "/items/missing", code: 404, desc: "item not found"},
+		{target: "/items/failed", code: 500, desc: "internal server error"},
+		{target: "/items/conflict", code: 409, desc: "conflict"},
+		{target: "/items/empty", code: 204},
+		{target: "/items/1", code: 201},
+	}
+
+	for _, tt := range tests {
+		w := serve(mn, "GET", tt.target, nil)
+		var resp Response
+		// 204 No Content has no body
+		if w.Body.Len() != 0 {
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if w.Code != tt.code || resp.Description != tt.desc {
+			t.Errorf("%s: status = %d, description %q, want %d, %q", tt.target, w.Code, resp.Description, tt.code, tt.desc)
+		}
+	}
+}
+
+// wrapErr wrap error, for testing errors.As
+type wrapErr struct {
+	err error
+}
+
+func (e wrapErr) Error() string { return "wrapped: " + e.err.Error() }
+func (e wrapErr) Unwrap() error { return e.err }
+
+func TestHandleContext(t *testing.T) {
+	var called []string
+	md := func(next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+			called = append(called, "middleware")
+			next(w, r, p)
+		}
+	}
+
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		ep.Gzip = true
+		Handle(ep, "GET", "/items/:id", func(ctx context.Context, req struct{}) (string, error) {
+			called = append(called, "handler")
+			r := RequestFromContext(ctx)
+			return r.Header.Get("X-Name") + httprouter.ParamsFromContext(ctx).ByName("id"), nil
+		}, md)
+	})
+
+	r := httptest.NewRequest("GET", "/items/1", nil)
+	r.Header.Set("X-Name", "item")
+	w := httptest.NewRecorder()
+	mn.ServeHTTP(w, r)
+	if got := gunzip(t, w.Body.Bytes()); got != `{"statusCode":200,"status":"ok","body":"item1"}`+"\n" {
+		t.Errorf("body = %q", got)
+	}
+
+	if strings.Join(called, ",") != "middleware,handler" {
+		t.Errorf("called = %v", called)
+	}
+
+	routes := mn.Routes()
+	if len(routes) != 1 || routes[0].Handler == "" || len(routes[0].Middlewares) != 1 {
+		t.Errorf("routes = %+v", routes)
+	}
+}
+
+func TestHandleRegistrationPanics(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(ep *Endpoints)
+		want     string
+	}{
+		{
+			name: "missing path variable",
+			register: func(ep *Endpoints) {
+				Handle(ep, "GET", "/users", func(ctx context.Context, req listUsers) (string, error) { return "", nil })
+			},
+			want: "path variable group",
+		},
+		{
+			name: "unsupported type",
+			register: func(ep *Endpoints) {
+				Handle(ep, "GET", "/users", func(ctx context.Context, req struct {
+					Filter map[string]string `query:"filter"`
+				}) (string, error) {
+					return "", nil
+				})
+			},
+			want: "unsupported type",
+		},
+		{
+			name: "non struct without body",
+			register: func(ep *Endpoints) {
+				Handle(ep, "GET", "/users", func(ctx context.Context, req []string) (string, error) { return "", nil })
+			},
+			want: "must be struct",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				rcv := recover()
+				if rcv == nil || !strings.Contains(rcv.(string), tt.want) {
+					t.Errorf("panic = %v, want %q", rcv, tt.want)
+				}
+			}()
+
+			newEndpointsApp(tt.register)
+		})
+	}
+}
+
+func TestHandleOpenAPI(t *testing.T) {
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		Handle(ep, "PUT", "/users/:id", func(ctx context.Context, req updateUser) (testBody, error) {
+			return testBody{}, nil
+		})
+	})
+
+	doc := mn.OpenAPIDocument(OpenAPIOption{Title: "test"})
+	op := doc.Paths["/users/{id}"].Put
+	var params []string
+	for _, param := range op.Parameters {
+		params = append(params, param.In+":"+param.Name)
+	}
+
+	if got := strings.Join(params, ","); got != "path:id,query:notify,query:tag,query:since" {
+		t.Errorf("parameters = %s", got)
+	}
+
+	body := doc.Components.Schemas["updateUser"]
+	if body == nil || len(body.Properties) != 2 || body.Properties["name"] == nil {
+		t.Errorf("request body schema = %+v", body)
+	}
+
+	if _, ok := op.Responses["400"]; !ok {
+		t.Error("bad request response is not documented")
+	}
+
+	resp := op.Responses["200"].Content["application/json"].Schema
+	if raw, _ := json.Marshal(resp); !strings.Contains(string(raw), "testBody") {
+		t.Errorf("response schema = %s", raw)
+	}
+}
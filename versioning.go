@@ -0,0 +1,175 @@
+package minirest
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// VersionStrategy set how API version is selected from request
+type VersionStrategy int
+
+// Version strategies
+const (
+	// VersionByPath select version by URL prefix, such as /v1/users
+	VersionByPath VersionStrategy = iota
+	// VersionByAccept select version by "version" parameter of Accept media type,
+	// such as "Accept: application/json; version=v1"
+	VersionByAccept
+	// VersionByHeader select version by custom header, see VersionOption.Header
+	VersionByHeader
+)
+
+// default header for VersionByHeader
+const defaultVersionHeader = "API-Version"
+
+// VersionOption set options for API versioning
+type VersionOption struct {
+	Strategy VersionStrategy
+	// Header is request header holding the version for VersionByHeader,
+	// default is API-Version
+	Header string
+	// Default is version used for request without version.
+	// With VersionByPath, endpoints of Default version are also served without prefix
+	Default string
+	// Deprecated is deprecated versions, responses of these versions
+	// have Deprecation and Sunset headers
+	Deprecated map[string]Deprecation
+}
+
+// Deprecation describe deprecated API version, see RFC 9745 and RFC 8594
+type Deprecation struct {
+	// Date is when the version is deprecated, Deprecation header is "true" if zero
+	Date time.Time
+	// Sunset is when the version will be removed, Sunset header is omitted if zero
+	Sunset time.Time
+	// Link is URL of deprecation documentation
+	Link string
+}
+
+// versionRoutes is handles of a route for each version,
+// used by VersionByAccept and VersionByHeader
+type versionRoutes struct {
+	handles map[string]httprouter.Handle
+}
+
+// Versioning set API versioning for endpoints with version, see Endpoints.Version.
+// Versioning must be called before AddController.
+// Without Versioning, versioned endpoints are selected by URL prefix
+func (mn *Minirest) Versioning(opt VersionOption) {
+	if opt.Header == "" {
+		opt.Header = defaultVersionHeader
+	}
+
+	mn.versioning = opt
+}
+
+// handleVersion register handle of version
+func (mn *Minirest) handleVersion(scope routeScope, info RouteInfo, handle httprouter.Handle) {
+	opt := mn.versioning
+	method, path := info.Method, info.Path
+	handle = deprecationHandler(opt.Deprecated, scope.version, handle)
+	if opt.Strategy == VersionByPath {
+		prefixed := "/" + strings.Trim(scope.version, "/") + path
+		mn.register(scope, method, prefixed, handle)
+		mn.addRoute(info, prefixed)
+		if scope.version == opt.Default {
+			mn.register(scope, method, path, handle)
+			mn.addRoute(info, path)
+		}
+
+		return
+	}
+
+	key := method + " " + path
+	routes, ok := mn.versions[key]
+	if !ok {
+		routes = &versionRoutes{handles: make(map[string]httprouter.Handle)}
+		mn.versions[key] = routes
+		mn.register(scope, method, path, mn.versionDispatcher(routes))
+	}
+
+	routes.handles[scope.version] = handle
+	mn.addRoute(info, path)
+}
+
+// versionDispatcher call handle of version requested by request
+func (mn *Minirest) versionDispatcher(routes *versionRoutes) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		opt := mn.versioning
+		var version string
+		if opt.Strategy == VersionByAccept {
+			w.Header().Add("Vary", "Accept")
+			version = acceptVersion(r.Header.Get("Accept"))
+		} else {
+			w.Header().Add("Vary", opt.Header)
+			version = r.Header.Get(opt.Header)
+		}
+
+		if version == "" {
+			version = opt.Default
+		}
+
+		handle, ok := routes.handles[version]
+		if !ok {
+			resp := new(ResponseBuilder)
+			if opt.Strategy == VersionByAccept {
+				resp.Error(http.StatusNotAcceptable, "unsupported API version "+version, nil)
+			} else {
+				resp.BadRequest("unsupported API version " + version)
+			}
+
+			mn.writeResponse(w, r, resp)
+			return
+		}
+
+		handle(w, r, p)
+	}
+}
+
+// acceptVersion return "version" parameter of the first media type that has it
+func acceptVersion(accept string) string {
+	for _, mediaType := range strings.Split(accept, ",") {
+		_, params, err := mime.ParseMediaType(strings.TrimSpace(mediaType))
+		if err != nil {
+			continue
+		}
+
+		if v := params["version"]; v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// deprecationHandler add Deprecation, Sunset and Link headers to responses of deprecated version
+func deprecationHandler(deprecated map[string]Deprecation, version string, next httprouter.Handle) httprouter.Handle {
+	dep, ok := deprecated[version]
+	if !ok {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		header := w.Header()
+		if dep.Date.IsZero() {
+			header.Set("Deprecation", "true")
+		} else {
+			header.Set("Deprecation", "@"+strconv.FormatInt(dep.Date.Unix(), 10))
+		}
+
+		if !dep.Sunset.IsZero() {
+			header.Set("Sunset", dep.Sunset.UTC().Format(http.TimeFormat))
+		}
+
+		if dep.Link != "" {
+			header.Add("Link", "<"+dep.Link+`>; rel="deprecation"`)
+		}
+
+		next(w, r, p)
+	}
+}
@@ -0,0 +1,217 @@
+package minirest
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// registerVersions register /users in v1 and v2, and /users/:id only in v2
+func registerVersions(ep *Endpoints) {
+	v1 := ep.Group("")
+	v1.Version("v1")
+	v1.GET("/users", func() *ResponseBuilder { return echo("v1") })
+
+	v2 := ep.Group("")
+	v2.Version("v2")
+	v2.GET("/users", func() *ResponseBuilder { return echo("v2") })
+	v2.GET("/users/:id", func(id int) *ResponseBuilder { return echo(id) })
+}
+
+var testDeprecated = map[string]Deprecation{
+	"v1": {
+		Date:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Sunset: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Link:   "https://example.com/deprecations/v1",
+	},
+}
+
+func TestVersionByPath(t *testing.T) {
+	mn := New()
+	mn.Versioning(VersionOption{Default: "v2", Deprecated: testDeprecated})
+	mn.AddController(&endpointsController{register: registerVersions})
+	tests := []struct {
+		target string
+		status int
+		body   interface{}
+	}{
+		{target: "/v1/users", status: 200, body: "v1"},
+		{target: "/v2/users", status: 200, body: "v2"},
+		{target: "/users", status: 200, body: "v2"},
+		{target: "/users/3", status: 200, body: float64(3)},
+		{target: "/v1/users/3", status: 404},
+		{target: "/v3/users", status: 404},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			w := serve(mn, "GET", tt.target, nil)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+
+			if tt.body != nil {
+				if got := decodeBody(t, w); got != tt.body {
+					t.Errorf("body = %v, want %v", got, tt.body)
+				}
+			}
+		})
+	}
+
+	w := serve(mn, "GET", "/v1/users", nil)
+	want := map[string]string{
+		"Deprecation": "@1704067200",
+		"Sunset":      "Wed, 01 Jan 2025 00:00:00 GMT",
+		"Link":        `<https://example.com/deprecations/v1>; rel="deprecation"`,
+	}
+
+	for key, value := range want {
+		if got := w.Header().Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+
+	if got := serve(mn, "GET", "/v2/users", nil).Header().Get("Deprecation"); got != "" {
+		t.Errorf("Deprecation of v2 = %q", got)
+	}
+}
+
+func TestVersionByHeader(t *testing.T) {
+	mn := New()
+	mn.Versioning(VersionOption{Strategy: VersionByHeader, Header: "X-Version", Default: "v1",
+		Deprecated: map[string]Deprecation{"v1": {}}})
+	mn.AddController(&endpointsController{register: registerVersions})
+	tests := []struct {
+		name       string
+		version    string
+		status     int
+		body       interface{}
+		deprecated bool
+	}{
+		{name: "default", status: 200, body: "v1", deprecated: true},
+		{name: "v1", version: "v1", status: 200, body: "v1", deprecated: true},
+		{name: "v2", version: "v2", status: 200, body: "v2"},
+		{name: "unsupported", version: "v9", status: 400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/users", nil)
+			if tt.version != "" {
+				r.Header.Set("X-Version", tt.version)
+			}
+
+			w := httptest.NewRecorder()
+			mn.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+
+			if got := w.Header().Get("Vary"); got != "X-Version" {
+				t.Errorf("Vary = %q", got)
+			}
+
+			if tt.body != nil {
+				if got := decodeBody(t, w); got != tt.body {
+					t.Errorf("body = %v, want %v", got, tt.body)
+				}
+			}
+
+			// deprecated version without date is only marked as deprecated
+			if got := w.Header().Get("Deprecation"); (got == "true") != tt.deprecated {
+				t.Errorf("Deprecation = %q", got)
+			}
+		})
+	}
+
+	// endpoint only in v2 is not served for default v1
+	if w := serve(mn, "GET", "/users/1", nil); w.Code != 400 {
+		t.Errorf("status of route missing in default version = %d, want 400", w.Code)
+	}
+}
+
+func TestVersionByAccept(t *testing.T) {
+	mn := New()
+	mn.Versioning(VersionOption{Strategy: VersionByAccept, Default: "v2"})
+	mn.AddController(&endpointsController{register: registerVersions})
+	tests := []struct {
+		accept string
+		status int
+		body   interface{}
+	}{
+		{accept: "", status: 200, body: "v2"},
+		{accept: "application/json; version=v1", status: 200, body: "v1"},
+		{accept: "text/html, application/json;version=v1;q=0.9", status: 200, body: "v1"},
+		{accept: "application/json", status: 200, body: "v2"},
+		{accept: "application/json; version=v9", status: 406},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/users", nil)
+			r.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+			mn.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+
+			if got := w.Header().Get("Vary"); got != "Accept" {
+				t.Errorf("Vary = %q", got)
+			}
+
+			if tt.body != nil {
+				if got := decodeBody(t, w); got != tt.body {
+					t.Errorf("body = %v, want %v", got, tt.body)
+				}
+			}
+		})
+	}
+}
+
+// versions selected by header share method and path, but not cached responses
+func TestVersionCache(t *testing.T) {
+	cache := NewResponseCache(10)
+	mn := New()
+	mn.Versioning(VersionOption{Strategy: VersionByHeader, Default: "v1"})
+	mn.AddController(&endpointsController{register: func(ep *Endpoints) {
+		ep.Cache(cache, CacheOption{TTL: time.Minute})
+		v1 := ep.Group("")
+		v1.Version("v1")
+		v1.GET("/users", func() *ResponseBuilder { return echo("v1") })
+
+		v2 := ep.Group("")
+		v2.Version("v2")
+		v2.GET("/users", func() *ResponseBuilder { return echo("v2") })
+	}})
+
+	for _, version := range []string{"v1", "v2", "v1", "v2"} {
+		r := httptest.NewRequest("GET", "/users", nil)
+		r.Header.Set(defaultVersionHeader, version)
+		w := httptest.NewRecorder()
+		mn.ServeHTTP(w, r)
+		if got := decodeBody(t, w); got != version {
+			t.Errorf("body of %s = %v", version, got)
+		}
+	}
+
+	if cache.Len() != 2 {
+		t.Errorf("cached %d responses, want 2", cache.Len())
+	}
+}
+
+func TestAcceptVersion(t *testing.T) {
+	tests := map[string]string{
+		"":                                      "",
+		"application/json":                      "",
+		"application/json; version=2":           "2",
+		`application/json; version="v1.2"`:      "v1.2",
+		"invalid;;, application/json;version=3": "3",
+	}
+
+	for accept, want := range tests {
+		if got := acceptVersion(accept); got != want {
+			t.Errorf("acceptVersion(%q) = %q, want %q", accept, got, want)
+		}
+	}
+}
@@ -0,0 +1,594 @@
+package minirest
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// WebSocket message types
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+)
+
+// WebSocket close codes
+const (
+	CloseNormalClosure   = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	CloseNoStatus        = 1005
+	CloseInvalidPayload  = 1007
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+)
+
+// frame opcodes
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// default options for WebSocket connection
+const (
+	defaultWSReadLimit    = 1 << 20
+	defaultWSPingInterval = 30 * time.Second
+	defaultWSWriteWait    = 10 * time.Second
+)
+
+// magic GUID for computing Sec-WebSocket-Accept, see RFC 6455 section 1.3
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+var (
+	// ErrReadLimit returned when received message is larger than read limit
+	ErrReadLimit = errors.New("websocket: message exceeds read limit")
+	// ErrConnClosed returned when writing to closed connection
+	ErrConnClosed = errors.New("websocket: connection is closed")
+)
+
+// CloseError returned by WebSocketConn read methods when close frame is received
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return "websocket: close " + strconv.Itoa(e.Code) + " " + e.Text
+}
+
+// WebSocketOption set options for WebSocket connections
+type WebSocketOption struct {
+	// ReadLimit is max size of a message in bytes, default is 1MB
+	ReadLimit int64
+	// PingInterval is interval of ping sent to client, default is 30 seconds.
+	// Set to negative value to disable ping
+	PingInterval time.Duration
+	// PongWait is max time to wait for the next message or pong from client,
+	// default is twice of PingInterval
+	PongWait time.Duration
+	// WriteWait is max time for writing a message, default is 10 seconds
+	WriteWait time.Duration
+	// Subprotocols is supported subprotocols, ordered by preference
+	Subprotocols []string
+	// CheckOrigin validate Origin header. If nil, request with Origin header
+	// is only accepted when the origin host is the same as Host header
+	CheckOrigin func(r *http.Request) bool
+}
+
+// WebSocketConn is an upgraded WebSocket connection.
+// Only one goroutine may read from connection at a time,
+// while writes are safe to use concurrently
+type WebSocketConn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	r           *http.Request
+	params      httprouter.Params
+	subprotocol string
+	opt         WebSocketOption
+	readLimit   int64
+	writeMu     sync.Mutex
+	closeOnce   sync.Once
+	closed      chan struct{}
+}
+
+// ReadMessage read the next message, ping and pong frames are handled internally
+func (c *WebSocketConn) ReadMessage() (msgType int, data []byte, err error) {
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch op {
+		case opPing:
+			c.extendReadDeadline()
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+
+			continue
+		case opPong:
+			c.extendReadDeadline()
+			continue
+		case opClose:
+			closeErr := &CloseError{Code: CloseNoStatus}
+			if len(payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Text = string(payload[2:])
+			}
+
+			c.closeWithStatus(closeErr.Code, "")
+			return 0, nil, closeErr
+		case opText, opBinary:
+			if msgType != 0 {
+				c.closeWithStatus(CloseProtocolError, "expected continuation frame")
+				return 0, nil, errors.New("websocket: expected continuation frame")
+			}
+
+			msgType = int(op)
+		case opContinuation:
+			if msgType == 0 {
+				c.closeWithStatus(CloseProtocolError, "unexpected continuation frame")
+				return 0, nil, errors.New("websocket: unexpected continuation frame")
+			}
+		default:
+			c.closeWithStatus(CloseProtocolError, "unknown opcode")
+			return 0, nil, errors.New("websocket: unknown opcode " + strconv.Itoa(int(op)))
+		}
+
+		c.extendReadDeadline()
+		if int64(len(data)+len(payload)) > c.readLimit {
+			c.closeWithStatus(CloseMessageTooBig, "")
+			return 0, nil, ErrReadLimit
+		}
+
+		data = append(data, payload...)
+		if fin {
+			if msgType == TextMessage && !utf8.Valid(data) {
+				c.closeWithStatus(CloseInvalidPayload, "invalid utf-8")
+				return 0, nil, errors.New("websocket: invalid utf-8 in text message")
+			}
+
+			return msgType, data, nil
+		}
+	}
+}
+
+// WriteMessage write message with type TextMessage or BinaryMessage
+func (c *WebSocketConn) WriteMessage(msgType int, data []byte) error {
+	if msgType != TextMessage && msgType != BinaryMessage {
+		return errors.New("websocket: invalid message type " + strconv.Itoa(msgType))
+	}
+
+	return c.writeFrame(byte(msgType), data)
+}
+
+// ReadJSON read the next message and decode it as JSON into v
+func (c *WebSocketConn) ReadJSON(v interface{}) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// WriteJSON encode v as JSON and write it as text message
+func (c *WebSocketConn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return c.writeFrame(opText, data)
+}
+
+// SetReadLimit set max size of a message in bytes.
+// Connection is closed with CloseMessageTooBig when the limit is exceeded
+func (c *WebSocketConn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+// Subprotocol return negotiated subprotocol
+func (c *WebSocketConn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// Request return the upgrade request
+func (c *WebSocketConn) Request() *http.Request {
+	return c.r
+}
+
+// Params return path variables
+func (c *WebSocketConn) Params() httprouter.Params {
+	return c.params
+}
+
+// Done return channel that closed when connection is closed
+func (c *WebSocketConn) Done() <-chan struct{} {
+	return c.closed
+}
+
+// Close close connection with CloseNormalClosure
+func (c *WebSocketConn) Close() error {
+	return c.CloseWithStatus(CloseNormalClosure, "")
+}
+
+// CloseWithStatus send close frame with code and reason, then close the connection
+func (c *WebSocketConn) CloseWithStatus(code int, reason string) error {
+	if !c.closeWithStatus(code, reason) {
+		return ErrConnClosed
+	}
+
+	return nil
+}
+
+// closeWithStatus send close frame and close the connection.
+// CloseNoStatus must not be sent, see RFC 6455 section 7.4.1,
+// so close frame without status is sent instead
+func (c *WebSocketConn) closeWithStatus(code int, reason string) bool {
+	closed := false
+	c.closeOnce.Do(func() {
+		var payload []byte
+		if code != CloseNoStatus {
+			payload = make([]byte, 2, 2+len(reason))
+			binary.BigEndian.PutUint16(payload, uint16(code))
+			payload = append(payload, reason...)
+		}
+
+		c.writeFrame(opClose, payload)
+		close(c.closed)
+		c.conn.Close()
+		closed = true
+	})
+
+	return closed
+}
+
+func (c *WebSocketConn) extendReadDeadline() {
+	if c.opt.PongWait > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.opt.PongWait))
+	}
+}
+
+func (c *WebSocketConn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(c.br, head[:]); err != nil {
+		return
+	}
+
+	fin = head[0]&0x80 != 0
+	op = head[0] & 0x0f
+	if head[0]&0x70 != 0 {
+		c.closeWithStatus(CloseProtocolError, "reserved bits are set")
+		return false, 0, nil, errors.New("websocket: reserved bits are set")
+	}
+
+	// client frames must be masked, see RFC 6455 section 5.1
+	if head[1]&0x80 == 0 {
+		c.closeWithStatus(CloseProtocolError, "frame is not masked")
+		return false, 0, nil, errors.New("websocket: frame is not masked")
+	}
+
+	length := int64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+
+	if op >= opClose && (!fin || length > 125) {
+		c.closeWithStatus(CloseProtocolError, "invalid control frame")
+		return false, 0, nil, errors.New("websocket: invalid control frame")
+	}
+
+	if length < 0 || length > c.readLimit {
+		c.closeWithStatus(CloseMessageTooBig, "")
+		return false, 0, nil, ErrReadLimit
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+		return
+	}
+
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return
+}
+
+func (c *WebSocketConn) writeFrame(op byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	select {
+	case <-c.closed:
+		return ErrConnClosed
+	default:
+	}
+
+	frame := make([]byte, 0, len(payload)+10)
+	frame = append(frame, 0x80|op)
+	switch {
+	case len(payload) <= 125:
+		frame = append(frame, byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, 126, byte(len(payload)>>8), byte(len(payload)))
+	default:
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(len(payload)))
+		frame = append(frame, 127)
+		frame = append(frame, ext[:]...)
+	}
+
+	frame = append(frame, payload...)
+	c.conn.SetWriteDeadline(time.Now().Add(c.opt.WriteWait))
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+func (c *WebSocketConn) keepalive() {
+	ticker := time.NewTicker(c.opt.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-ticker.C:
+			if err := c.writeFrame(opPing, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (opt WebSocketOption) withDefaults() WebSocketOption {
+	if opt.ReadLimit <= 0 {
+		opt.ReadLimit = defaultWSReadLimit
+	}
+
+	if opt.PingInterval == 0 {
+		opt.PingInterval = defaultWSPingInterval
+	}
+
+	if opt.PongWait == 0 && opt.PingInterval > 0 {
+		opt.PongWait = 2 * opt.PingInterval
+	}
+
+	if opt.WriteWait <= 0 {
+		opt.WriteWait = defaultWSWriteWait
+	}
+
+	if opt.CheckOrigin == nil {
+		opt.CheckOrigin = sameOrigin
+	}
+
+	return opt
+}
+
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, v := range header[http.CanonicalHeaderKey(name)] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func selectSubprotocol(r *http.Request, supported []string) string {
+	requested := strings.Split(r.Header.Get("Sec-WebSocket-Protocol"), ",")
+	for _, s := range supported {
+		for _, req := range requested {
+			if strings.TrimSpace(req) == s {
+				return s
+			}
+		}
+	}
+
+	return ""
+}
+
+// wrapper for WebSocket endpoint. Connection is closed when callback returns
+func (mn *Minirest) handleWebSocket(callback func(*WebSocketConn), opt WebSocketOption) httprouter.Handle {
+	opt = opt.withDefaults()
+	return func(w http.ResponseWriter, r *http.Request, pathVars httprouter.Params) {
+		writer := new(ResponseBuilder)
+		if r.Method != http.MethodGet ||
+			!headerContainsToken(r.Header, "Connection", "upgrade") ||
+			!headerContainsToken(r.Header, "Upgrade", "websocket") {
+			writer.BadRequest("websocket: not a websocket handshake")
+			mn.writeResponse(w, r, writer)
+			return
+		}
+
+		if r.Header.Get("Sec-WebSocket-Version") != "13" {
+			w.Header().Set("Sec-WebSocket-Version", "13")
+			writer.BadRequest("websocket: unsupported version")
+			mn.writeResponse(w, r, writer)
+			return
+		}
+
+		key := r.Header.Get("Sec-WebSocket-Key")
+		if key == "" {
+			writer.BadRequest("websocket: missing Sec-WebSocket-Key")
+			mn.writeResponse(w, r, writer)
+			return
+		}
+
+		if !opt.CheckOrigin(r) {
+			writer.Forbidden("websocket: origin not allowed")
+			mn.writeResponse(w, r, writer)
+			return
+		}
+
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			writer.InternalError("websocket: connection can not be hijacked")
+			mn.writeResponse(w, r, writer)
+			return
+		}
+
+		conn, brw, err := hijacker.Hijack()
+		if err != nil {
+			writer.InternalError(err.Error())
+			mn.writeResponse(w, r, writer)
+			return
+		}
+
+		sum := sha1.Sum([]byte(key + wsGUID))
+		subprotocol := selectSubprotocol(r, opt.Subprotocols)
+
+		// headers set by middlewares, such as cookies, are kept in handshake response
+		header := w.Header().Clone()
+		header.Set("Upgrade", "websocket")
+		header.Set("Connection", "Upgrade")
+		header.Set("Sec-WebSocket-Accept", base64.StdEncoding.EncodeToString(sum[:]))
+		if subprotocol != "" {
+			header.Set("Sec-WebSocket-Protocol", subprotocol)
+		}
+
+		conn.SetDeadline(time.Time{})
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+		header.Write(brw)
+		brw.WriteString("\r\n")
+		if err := brw.Flush(); err != nil {
+			conn.Close()
+			return
+		}
+
+		wsConn := &WebSocketConn{
+			conn:        conn,
+			br:          brw.Reader,
+			r:           r,
+			params:      pathVars,
+			subprotocol: subprotocol,
+			opt:         opt,
+			readLimit:   opt.ReadLimit,
+			closed:      make(chan struct{}),
+		}
+
+		if !mn.trackConn(wsConn) {
+			wsConn.CloseWithStatus(CloseGoingAway, "server shutdown")
+			return
+		}
+
+		defer mn.untrackConn(wsConn)
+
+		wsConn.extendReadDeadline()
+		if opt.PingInterval > 0 {
+			go wsConn.keepalive()
+		}
+
+		callback(wsConn)
+		wsConn.Close()
+	}
+}
+
+func (mn *Minirest) trackConn(conn *WebSocketConn) bool {
+	mn.mu.Lock()
+	defer mn.mu.Unlock()
+	if mn.isShutdown() {
+		return false
+	}
+
+	mn.wsConns[conn] = struct{}{}
+	return true
+}
+
+func (mn *Minirest) untrackConn(conn *WebSocketConn) {
+	mn.mu.Lock()
+	delete(mn.wsConns, conn)
+	mn.mu.Unlock()
+}
+
+// Shutdown gracefully shut down the server. Open WebSocket connections are closed
+// with CloseGoingAway and SSE streams are ended, then Shutdown waits for
+// active requests until ctx is done
+func (mn *Minirest) Shutdown(ctx context.Context) error {
+	mn.mu.Lock()
+	if !mn.isShutdown() {
+		close(mn.shutdown)
+	}
+
+	conns := make([]*WebSocketConn, 0, len(mn.wsConns))
+	for conn := range mn.wsConns {
+		conns = append(conns, conn)
+	}
+
+	server := mn.server
+	mn.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.CloseWithStatus(CloseGoingAway, "server shutdown")
+	}
+
+	if server == nil {
+		return nil
+	}
+
+	return server.Shutdown(ctx)
+}
+
+func (mn *Minirest) isShutdown() bool {
+	select {
+	case <-mn.shutdown:
+		return true
+	default:
+		return false
+	}
+}
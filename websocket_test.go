@@ -0,0 +1,425 @@
+package minirest
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wsClient is raw WebSocket client for testing frames sent over the wire
+type wsClient struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+	resp *http.Response
+}
+
+// dialWebSocket send handshake for path to server, extra headers are added to the request
+func dialWebSocket(t *testing.T, srv *httptest.Server, path string, extra ...string) *wsClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req := "GET " + path + " HTTP/1.1\r\nHost: " + conn.RemoteAddr().String() + "\r\n" +
+		"Connection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Version: 13\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"
+	for i := 0; i+1 < len(extra); i += 2 {
+		req += extra[i] + ": " + extra[i+1] + "\r\n"
+	}
+
+	if _, err := conn.Write([]byte(req + "\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &wsClient{t: t, conn: conn, br: br, resp: resp}
+}
+
+// write write masked frame, first is the byte holding fin bit and opcode
+func (c *wsClient) write(first byte, payload []byte) {
+	c.t.Helper()
+	c.writeRaw(first, payload, true)
+}
+
+func (c *wsClient) writeRaw(first byte, payload []byte, masked bool) {
+	c.t.Helper()
+	frame := []byte{first}
+	maskBit := byte(0)
+	if masked {
+		maskBit = 0x80
+	}
+
+	switch {
+	case len(payload) <= 125:
+		frame = append(frame, maskBit|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, maskBit|126, byte(len(payload)>>8), byte(len(payload)))
+	default:
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(len(payload)))
+		frame = append(append(frame, maskBit|127), ext[:]...)
+	}
+
+	data := append([]byte(nil), payload...)
+	if masked {
+		mask := [4]byte{1, 2, 3, 4}
+		frame = append(frame, mask[:]...)
+		for i := range data {
+			data[i] ^= mask[i%4]
+		}
+	}
+
+	if _, err := c.conn.Write(append(frame, data...)); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// read read unmasked frame sent by server
+func (c *wsClient) read() (first byte, payload []byte) {
+	c.t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		c.t.Fatalf("read frame: %v", err)
+	}
+
+	if head[1]&0x80 != 0 {
+		c.t.Fatal("server frame is masked")
+	}
+
+	length := int(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(c.br, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(c.br, ext[:])
+		length = int(binary.BigEndian.Uint64(ext[:]))
+	}
+
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		c.t.Fatalf("read payload: %v", err)
+	}
+
+	return head[0], payload
+}
+
+// readClose read close frame and return its status code, 0 if it has no status
+func (c *wsClient) readClose() int {
+	c.t.Helper()
+	first, payload := c.read()
+	if first != 0x80|opClose {
+		c.t.Fatalf("frame = %#x %q, want close", first, payload)
+	}
+
+	if len(payload) == 0 {
+		return 0
+	}
+
+	return int(binary.BigEndian.Uint16(payload))
+}
+
+func newWebSocketServer(t *testing.T, opt *WebSocketOption, handler func(conn *WebSocketConn)) (*Minirest, *httptest.Server) {
+	mn := newEndpointsApp(func(ep *Endpoints) {
+		if opt != nil {
+			ep.WebSocketOptions(*opt)
+		}
+
+		ep.WebSocket("/ws/:room", handler)
+	})
+
+	srv := httptest.NewServer(mn)
+	t.Cleanup(srv.Close)
+
+	return mn, srv
+}
+
+// echoWebSocket echo messages until connection is closed, and report the read error
+func echoWebSocket(errc chan<- error) func(conn *WebSocketConn) {
+	return func(conn *WebSocketConn) {
+		for {
+			msgType, data, err := conn.ReadMessage()
+			if err != nil {
+				errc <- err
+				return
+			}
+
+			conn.WriteMessage(msgType, data)
+		}
+	}
+}
+
+func TestWebSocketHandshake(t *testing.T) {
+	var room, subprotocol string
+	_, srv := newWebSocketServer(t, &WebSocketOption{Subprotocols: []string{"v2", "v1"}}, func(conn *WebSocketConn) {
+		room = conn.Params().ByName("room")
+		subprotocol = conn.Subprotocol()
+	})
+
+	c := dialWebSocket(t, srv, "/ws/lobby", "Sec-WebSocket-Protocol", "v1, v2")
+	if c.resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d", c.resp.StatusCode)
+	}
+
+	// example key and accept from RFC 6455 section 1.3
+	if got := c.resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Sec-WebSocket-Accept = %q", got)
+	}
+
+	if got := c.resp.Header.Get("Sec-WebSocket-Protocol"); got != "v2" {
+		t.Errorf("Sec-WebSocket-Protocol = %q", got)
+	}
+
+	// connection is closed normally when handler returns
+	if code := c.readClose(); code != CloseNormalClosure {
+		t.Errorf("close code = %d", code)
+	}
+
+	if room != "lobby" || subprotocol != "v2" {
+		t.Errorf("room = %q, subprotocol = %q", room, subprotocol)
+	}
+}
+
+func TestWebSocketHandshakeRejected(t *testing.T) {
+	_, srv := newWebSocketServer(t, nil, func(conn *WebSocketConn) {
+		t.Error("handler is called")
+	})
+
+	tests := []struct {
+		name   string
+		header [][2]string
+		code   int
+	}{
+		{name: "not upgrade", code: http.StatusBadRequest},
+		{
+			name:   "unsupported version",
+			header: [][2]string{{"Connection", "Upgrade"}, {"Upgrade", "websocket"}, {"Sec-WebSocket-Version", "8"}},
+			code:   http.StatusBadRequest,
+		},
+		{
+			name:   "missing key",
+			header: [][2]string{{"Connection", "Upgrade"}, {"Upgrade", "websocket"}, {"Sec-WebSocket-Version", "13"}},
+			code:   http.StatusBadRequest,
+		},
+		{
+			name: "cross origin",
+			header: [][2]string{
+				{"Connection", "Upgrade"}, {"Upgrade", "websocket"}, {"Sec-WebSocket-Version", "13"},
+				{"Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ=="}, {"Origin", "https://evil.example"},
+			},
+			code: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", srv.URL+"/ws/lobby", nil)
+			for _, h := range tt.header {
+				req.Header.Set(h[0], h[1])
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp.Body.Close()
+			if resp.StatusCode != tt.code {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.code)
+			}
+		})
+	}
+}
+
+func TestWebSocketMessages(t *testing.T) {
+	errc := make(chan error, 1)
+	_, srv := newWebSocketServer(t, nil, echoWebSocket(errc))
+	c := dialWebSocket(t, srv, "/ws/lobby")
+
+	c.write(0x80|opText, []byte("hello"))
+	if first, payload := c.read(); first != 0x80|opText || string(payload) != "hello" {
+		t.Errorf("echo = %#x %q", first, payload)
+	}
+
+	// 16 bit and 64 bit payload lengths
+	for _, size := range []int{300, 70000} {
+		data := []byte(strings.Repeat("b", size))
+		c.write(0x80|opBinary, data)
+		if first, payload := c.read(); first != 0x80|opBinary || len(payload) != size {
+			t.Errorf("echo of %d bytes = %#x, %d bytes", size, first, len(payload))
+		}
+	}
+
+	// fragmented message with ping in between
+	c.write(opText, []byte("frag"))
+	c.write(0x80|opPing, []byte("p"))
+	if first, payload := c.read(); first != 0x80|opPong || string(payload) != "p" {
+		t.Errorf("pong = %#x %q", first, payload)
+	}
+
+	c.write(opContinuation, []byte("men"))
+	c.write(0x80|opContinuation, []byte("ted"))
+	if _, payload := c.read(); string(payload) != "fragmented" {
+		t.Errorf("reassembled = %q", payload)
+	}
+
+	payload := []byte{0x03, 0xe8}
+	c.write(0x80|opClose, append(payload, "bye"...))
+	if code := c.readClose(); code != CloseNormalClosure {
+		t.Errorf("close code = %d", code)
+	}
+
+	var closeErr *CloseError
+	if err := <-errc; !errors.As(err, &closeErr) || closeErr.Code != CloseNormalClosure || closeErr.Text != "bye" {
+		t.Errorf("read error = %v", err)
+	}
+}
+
+func TestWebSocketCloseWithoutStatus(t *testing.T) {
+	errc := make(chan error, 1)
+	_, srv := newWebSocketServer(t, nil, echoWebSocket(errc))
+	c := dialWebSocket(t, srv, "/ws/lobby")
+
+	c.write(0x80|opClose, nil)
+	// 1005 must not be sent on the wire, see RFC 6455 section 7.4.1
+	if code := c.readClose(); code != 0 {
+		t.Errorf("close code = %d, want no status", code)
+	}
+
+	var closeErr *CloseError
+	if err := <-errc; !errors.As(err, &closeErr) || closeErr.Code != CloseNoStatus {
+		t.Errorf("read error = %v", err)
+	}
+}
+
+func TestWebSocketProtocolErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		send  func(c *wsClient)
+		code  int
+		limit int64
+	}{
+		{
+			name: "unmasked frame",
+			send: func(c *wsClient) { c.writeRaw(0x80|opText, []byte("x"), false) },
+			code: CloseProtocolError,
+		},
+		{
+			name: "reserved bits",
+			send: func(c *wsClient) { c.write(0xc0|opText, []byte("x")) },
+			code: CloseProtocolError,
+		},
+		{
+			name: "unknown opcode",
+			send: func(c *wsClient) { c.write(0x80|0x3, []byte("x")) },
+			code: CloseProtocolError,
+		},
+		{
+			name: "unexpected continuation",
+			send: func(c *wsClient) { c.write(0x80|opContinuation, []byte("x")) },
+			code: CloseProtocolError,
+		},
+		{
+			name: "expected continuation",
+			send: func(c *wsClient) {
+				c.write(opText, []byte("x"))
+				c.write(0x80|opText, []byte("y"))
+			},
+			code: CloseProtocolError,
+		},
+		{
+			name: "fragmented control frame",
+			send: func(c *wsClient) { c.write(opPing, nil) },
+			code: CloseProtocolError,
+		},
+		{
+			name: "invalid utf-8",
+			send: func(c *wsClient) { c.write(0x80|opText, []byte{0xff, 0xfe}) },
+			code: CloseInvalidPayload,
+		},
+		{
+			name:  "frame exceeds read limit",
+			send:  func(c *wsClient) { c.write(0x80|opBinary, make([]byte, 20)) },
+			code:  CloseMessageTooBig,
+			limit: 10,
+		},
+		{
+			name: "message exceeds read limit",
+			send: func(c *wsClient) {
+				c.write(opBinary, make([]byte, 8))
+				c.write(0x80|opContinuation, make([]byte, 8))
+			},
+			code:  CloseMessageTooBig,
+			limit: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errc := make(chan error, 1)
+			_, srv := newWebSocketServer(t, &WebSocketOption{ReadLimit: tt.limit}, echoWebSocket(errc))
+			c := dialWebSocket(t, srv, "/ws/lobby")
+
+			tt.send(c)
+			if code := c.readClose(); code != tt.code {
+				t.Errorf("close code = %d, want %d", code, tt.code)
+			}
+
+			if err := <-errc; err == nil {
+				t.Error("read error is nil")
+			}
+		})
+	}
+}
+
+func TestWebSocketPing(t *testing.T) {
+	_, srv := newWebSocketServer(t, &WebSocketOption{PingInterval: 10 * time.Millisecond}, func(conn *WebSocketConn) {
+		<-conn.Done()
+	})
+
+	c := dialWebSocket(t, srv, "/ws/lobby")
+	if first, _ := c.read(); first != 0x80|opPing {
+		t.Errorf("frame = %#x, want ping", first)
+	}
+}
+
+func TestWebSocketShutdown(t *testing.T) {
+	started := make(chan struct{})
+	mn, srv := newWebSocketServer(t, nil, func(conn *WebSocketConn) {
+		close(started)
+		conn.ReadMessage()
+	})
+
+	c := dialWebSocket(t, srv, "/ws/lobby")
+	<-started
+	mn.Shutdown(context.Background())
+	if code := c.readClose(); code != CloseGoingAway {
+		t.Errorf("close code = %d, want %d", code, CloseGoingAway)
+	}
+
+	// connection after shutdown is closed right after handshake
+	c = dialWebSocket(t, srv, "/ws/lobby")
+	if code := c.readClose(); code != CloseGoingAway {
+		t.Errorf("close code after shutdown = %d, want %d", code, CloseGoingAway)
+	}
+}